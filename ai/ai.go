@@ -0,0 +1,520 @@
+// Package ai has the battleship-playing AIs; the classic hunt and target AI, the sampler and learner,
+// and AIs playing precomputed strategies, along with the tools that tune them and analyse games.
+package ai
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/stewi1014/battleship"
+)
+
+// NewAI returns a new AI playing by the given rules, with randomly placed ships, and using the current Unix time as a rng seed.
+// It panics if the rules can't be played by; see Rules.Check.
+func NewAI(rules battleship.Rules) *AI {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return &AI{
+		rng:     rng,
+		rules:   rules,
+		arsenal: rules.Arsenal,
+		board:   battleship.RandomBoard(rng, rules),
+		params:  DefaultAIParams(),
+	}
+}
+
+// SetDifficulty places the AI's ships as it would for the given difficulty.
+func (a *AI) SetDifficulty(difficulty int) {
+	a.board = battleship.PlacementBoard(a.rng, a.rules, difficulty, nil)
+}
+
+// SetParams sets the weights the AI hunts with.
+func (a *AI) SetParams(params AIParams) {
+	a.params = params
+}
+
+// SetOpening sets the shots the AI hunts with until its first hit, as found in an opening book.
+func (a *AI) SetOpening(opening []battleship.Point) {
+	a.opening = opening
+}
+
+// AI is a simple battleship-playing AI.
+type AI struct {
+	board   battleship.Board
+	score   int
+	rules   battleship.Rules
+	arsenal battleship.Arsenal
+
+	// sunk holds hits we know are part of a ship that has been sunk.
+	sunk battleship.Bitboard
+	// hits and known hold the hits not known to be sunk, and the positions we know what is at, as of the attack being chosen.
+	hits, known battleship.Bitboard
+
+	params AIParams
+
+	// opening is the shots to hunt with until the first hit, from an opening book.
+	opening []battleship.Point
+	offBook bool
+
+	// output is shown the AI's board after each turn, and traceOutput the reason for each attack.
+	output, traceOutput io.Writer
+	fireLog
+
+	rng *rand.Rand
+}
+
+// SetOutput sets where the AI shows its board after each turn; nil, the default, shows nothing.
+func (a *AI) SetOutput(output io.Writer) {
+	a.output = output
+}
+
+// SetTrace sets where the AI explains each attack it makes; nil, the default, explains nothing.
+func (a *AI) SetTrace(trace io.Writer) {
+	a.traceOutput = trace
+}
+
+// trace writes a line explaining an AI's decision to w, if it's not nil.
+func trace(w io.Writer, format string, a ...interface{}) {
+	if w != nil {
+		fmt.Fprintf(w, format+"\n", a...)
+	}
+}
+
+// fireLog keeps track of the opponent's fire on an AI's board.
+// Embedding it implements Player's IncomingFire.
+type fireLog struct {
+	// incoming holds the opponent's shots since the AI's last turn, and lost counts the AI's ships they've sunk.
+	incoming []battleship.IncomingFire
+	lost     int
+}
+
+// IncomingFire implements Player.
+func (l *fireLog) IncomingFire(fire battleship.IncomingFire) {
+	l.incoming = append(l.incoming, fire)
+	if fire.Sunk != 0 {
+		l.lost++
+	}
+}
+
+// showBoard writes an AI's board, with the opponent's fire since its last turn highlighted, and the streak it had this turn, to w, if it's not nil.
+// The fire is then cleared, ready for the next turn.
+func (l *fireLog) showBoard(w io.Writer, board *battleship.Board, fleet battleship.Fleet, streak int) {
+	defer func() { l.incoming = nil }()
+	if w == nil {
+		return
+	}
+	fmt.Fprintln(w, "AI board")
+	fmt.Fprint(w, board.FormatIncoming(fleet, l.incoming))
+	if l.lost > 0 {
+		fmt.Fprintf(w, "AI has lost %v of %v ships\n", l.lost, len(fleet))
+	}
+	if streak > 0 {
+		fmt.Fprintln(w, "AI streak", streak)
+	}
+}
+
+// GetBoard implements Player.
+func (a *AI) GetBoard() *battleship.Board {
+	return &a.board
+}
+
+// Turn implements Player.
+func (a *AI) Turn(remote battleship.Link) (won bool, err error) {
+	var streak int
+
+	// show our board after we return
+	defer func() {
+		a.showBoard(a.output, &a.board, a.rules.GetFleet(), streak)
+	}()
+
+	for {
+		hit, sunk := battleship.Result(a.attack(remote))
+		if a.rules.Won(a.score) || !a.rules.ShootAgain(hit, sunk) {
+			return a.rules.Won(a.score), nil
+		}
+		streak++
+	}
+}
+
+// attack picks an attack and launches it, returning the shots that landed.
+func (a *AI) attack(remote battleship.Link) []battleship.Shot {
+	at, reason := a.chooseAttack()
+	trace(a.traceOutput, "AI attacks %v; %v", at, reason)
+	if at.Weapon == battleship.WeaponShot && a.board.PlayerHasShot(at.X, at.Y) {
+		panic("ai tried to hit point it already shot!")
+	}
+	if !a.arsenal.Use(at.Weapon) {
+		panic("ai tried to use a weapon it doesn't have!")
+	}
+
+	shots, _ := at.Launch(&a.board, remote)
+	for _, shot := range shots {
+		if shot.Hit {
+			a.offBook = true
+		}
+		if shot.Sunk != 0 {
+			a.score++
+			a.markSunk(shot.X, shot.Y, shot.Sunk)
+			if a.rules.NoTouching {
+				a.markHalo(shot.X, shot.Y)
+			}
+		}
+	}
+	return shots
+}
+
+// chooseAttack decides on the next attack to make, and gives the reason for it.
+func (a *AI) chooseAttack() (at battleship.Attack, reason string) {
+	bits := a.board.Bits()
+	a.hits, a.known = bits.Hits.AndNot(a.sunk), bits.Shots.Or(bits.Water)
+
+	// try to hit a previously hit ship.
+	for _, p := range a.hits.Points() {
+		if shootx, shooty, ok := a.findShot(p.X, p.Y); ok {
+			reason = "target mode, extending the hits at " + battleship.FormatPoints(a.cluster(p.X, p.Y))
+			// an airstrike is worth it if most of it will land on positions we don't know about.
+			if a.arsenal.Has(battleship.WeaponAirstrike) && a.unknownInRow(shootx, shooty, battleship.AirstrikeRadius) > a.params.Airstrike {
+				return battleship.Attack{Weapon: battleship.WeaponAirstrike, X: shootx, Y: shooty}, reason + ", where most of the row is unknown"
+			}
+			return battleship.Attack{Weapon: battleship.WeaponShot, X: shootx, Y: shooty}, reason
+		}
+	}
+
+	// no ships to follow up on; hunt for a new one.
+	if x, y, ok := a.findContactShot(bits.Contacts); ok {
+		return battleship.Attack{Weapon: battleship.WeaponShot, X: x, Y: y}, "hunt mode, searching where sonar made contact"
+	}
+	if a.arsenal.Has(battleship.WeaponSonar) {
+		x, y := a.getSonarArea()
+		return battleship.Attack{Weapon: battleship.WeaponSonar, X: x, Y: y}, "hunt mode, pinging the area with the most unknown positions"
+	}
+	if a.arsenal.Has(battleship.WeaponTorpedo) {
+		at := battleship.Attack{Weapon: battleship.WeaponTorpedo, Y: a.getTorpedoRow(), FromRight: a.rng.Intn(2) == 0}
+		return at, "hunt mode, along the row with the most unknown positions"
+	}
+
+	// no luck, follow the opening book or take a random shot
+	if x, y, ok := a.getBookShot(); ok {
+		return battleship.Attack{Weapon: battleship.WeaponShot, X: x, Y: y}, "hunt mode, following the opening book"
+	}
+	x, y := a.getRandomShot()
+	return battleship.Attack{Weapon: battleship.WeaponShot, X: x, Y: y}, "hunt mode, shooting at random"
+}
+
+// findShot checks if a point on the board is on a previous hit, and if so,
+// tries to hit the ship again. If sucessful, ok is true.
+func (a *AI) findShot(x, y int) (shootx int, shooty int, ok bool) {
+	if !a.hits.Has(x, y) {
+		// this point hasn't been hit, or the ship here is already sunk
+		return 0, 0, false
+	}
+
+	if !a.rules.GetFleet().Straight() {
+		// odd shaped ships can turn in any direction,
+		// so rather than following lines, try the points around the hit.
+		return a.findAdjacentShot(x, y)
+	}
+
+	// we've hit this point before. Check surrounding points to see if we can figure out which way the ship is placed.
+	var lines int
+	for _, line := range a.lineDirections() {
+		if a.hasHit(x-line.X, y-line.Y) || a.hasHit(x+line.X, y+line.Y) {
+			// we've hit a point next to the position along this line, the ship might be placed along it.
+			shootx, shooty, ok = a.findLineShot(x, y, line.X, line.Y)
+			if ok {
+				return shootx, shooty, true
+			}
+
+			lines++
+		}
+	}
+
+	if lines > 1 && !a.rules.NoTouching {
+		// we found hits in more than one line.
+		// it's possible two vertical, or two horizontal ships are next to eachother.
+		// if so, we need to hit the diagonals.
+		// Ships can't be next to eachother if they're not allowed to touch.
+		shootx, shooty, ok := a.findDiagonalShot(x, y)
+		if ok {
+			return shootx, shooty, true
+		}
+	}
+
+	if lines > 0 {
+		// we've already handled cases for points found in a line, so if a line is what was found,
+		// we know there's nothing to do at this point.
+		return 0, 0, false
+	}
+
+	// the point is hit, but no line was found.
+	// try hitting adjacent points.
+	return a.findAdjacentShot(x, y)
+}
+
+// lineDirections returns the directions ships can be placed along, as vectors.
+func (a *AI) lineDirections() []battleship.Point {
+	if a.rules.Diagonal {
+		return []battleship.Point{{X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: -1}}
+	}
+	return []battleship.Point{{X: 1, Y: 0}, {X: 0, Y: 1}}
+}
+
+// hasHit returns true if x,y is on the board, and we've hit a ship there.
+func (a *AI) hasHit(x, y int) bool {
+	return battleship.IsValid(x, y) && a.hits.Has(x, y)
+}
+
+// findLineShot tries to take a shot at a ship possibly placed along the line through x,y in the direction mx,my.
+// it returns true if a shot is found.
+func (a *AI) findLineShot(x, y, mx, my int) (int, int, bool) {
+	// check forwards
+	for ix, iy := x, y; battleship.IsValid(ix, iy); ix, iy = ix+mx, iy+my {
+		if a.hits.Has(ix, iy) {
+			// we've hit this point before
+			continue
+		}
+		if !a.unknown(ix, iy) {
+			// we've missed, or know there's water, at this position.
+			break
+		}
+		// found a point to shoot
+		return ix, iy, true
+	}
+
+	// check backwards; same thing in reverse
+	for ix, iy := x, y; battleship.IsValid(ix, iy); ix, iy = ix-mx, iy-my {
+		if a.hits.Has(ix, iy) {
+			continue
+		}
+		if !a.unknown(ix, iy) {
+			break
+		}
+		return ix, iy, true
+	}
+
+	return 0, 0, false
+}
+
+// findAdjacentShot searches for a shot in the positions next to the given position.
+// it returns true if a shot was found.
+func (a *AI) findAdjacentShot(x, y int) (int, int, bool) {
+	//up
+	if y+1 < battleship.BoardSize && a.unknown(x, y+1) {
+		return x, y + 1, true
+	}
+	// down
+	if y-1 >= 0 && a.unknown(x, y-1) {
+		return x, y - 1, true
+	}
+	// left
+	if x-1 >= 0 && a.unknown(x-1, y) {
+		return x - 1, y, true
+	}
+	// right
+	if x+1 < battleship.BoardSize && a.unknown(x+1, y) {
+		return x + 1, y, true
+	}
+
+	// diagonal ships can carry on from any corner.
+	if a.rules.Diagonal {
+		return a.findDiagonalShot(x, y)
+	}
+
+	// all adjacent points are already hit
+	return 0, 0, false
+}
+
+// findDiagonalShot searches for a shot in the positions immediately diagonal to the given position.
+// it returns true if a shot was found.
+func (a *AI) findDiagonalShot(x, y int) (int, int, bool) {
+	// bottom left
+	if x-1 >= 0 && y-1 >= 0 && a.unknown(x-1, y-1) {
+		return x - 1, y - 1, true
+	}
+	// bottom right
+	if x+1 < battleship.BoardSize && y-1 >= 0 && a.unknown(x+1, y-1) {
+		return x + 1, y - 1, true
+	}
+	// top right
+	if x+1 < battleship.BoardSize && y+1 < battleship.BoardSize && a.unknown(x+1, y+1) {
+		return x + 1, y + 1, true
+	}
+	// top left
+	if x-1 >= 0 && y+1 < battleship.BoardSize && a.unknown(x-1, y+1) {
+		return x - 1, y + 1, true
+	}
+
+	// all diagonal points are already hit
+	return 0, 0, false
+}
+
+// getBookShot returns the next shot in the opening book we know nothing about, until the first hit.
+// it returns true if a shot was found.
+func (a *AI) getBookShot() (int, int, bool) {
+	if a.offBook {
+		return 0, 0, false
+	}
+	for _, p := range a.opening {
+		if battleship.IsValid(p.X, p.Y) && a.unknown(p.X, p.Y) {
+			return p.X, p.Y, true
+		}
+	}
+	return 0, 0, false
+}
+
+// getRandomShot picks a random position we know nothing about, weighted by the AI's parameters.
+func (a *AI) getRandomShot() (x int, y int) {
+	unknown := a.known.Not().Points()
+	weights := make([]float64, len(unknown))
+	var total float64
+	for i, p := range unknown {
+		weights[i] = a.params.weight(a.rules, p.X, p.Y)
+		total += weights[i]
+	}
+
+	n := a.rng.Float64() * total
+	for i, p := range unknown {
+		if weights[i] == 0 {
+			continue
+		}
+		x, y = p.X, p.Y
+		if n -= weights[i]; n < 0 {
+			return
+		}
+	}
+	// rounding left n just over the last weight.
+	return
+}
+
+// findContactShot searches for a position a sonar ping found a ship near, that we know nothing else about.
+// it returns true if a shot was found.
+func (a *AI) findContactShot(contacts battleship.Bitboard) (int, int, bool) {
+	if points := contacts.AndNot(a.known).Points(); len(points) > 0 {
+		return points[0].X, points[0].Y, true
+	}
+	return 0, 0, false
+}
+
+// getSonarArea finds the centre of the sonar area with the most positions we know nothing about.
+func (a *AI) getSonarArea() (x int, y int) {
+	best := -1
+	// keep the whole area on the board, so none of the ping is wasted.
+	for ix := battleship.SonarRadius; ix < battleship.BoardSize-battleship.SonarRadius; ix++ {
+		for iy := battleship.SonarRadius; iy < battleship.BoardSize-battleship.SonarRadius; iy++ {
+			var count int
+			for jy := iy - battleship.SonarRadius; jy <= iy+battleship.SonarRadius; jy++ {
+				count += a.unknownInRow(ix, jy, battleship.SonarRadius)
+			}
+			if count > best {
+				x, y, best = ix, iy, count
+			}
+		}
+	}
+	return
+}
+
+// getTorpedoRow finds the row with the most positions we know nothing about.
+func (a *AI) getTorpedoRow() (y int) {
+	best := -1
+	for iy := 0; iy < battleship.BoardSize; iy++ {
+		if count := a.unknownInRow(battleship.BoardSize/2, iy, battleship.BoardSize); count > best {
+			y, best = iy, count
+		}
+	}
+	return
+}
+
+// unknownInRow counts the positions we know nothing about in the row segment centred on x,y.
+func (a *AI) unknownInRow(x, y, radius int) int {
+	var row battleship.Bitboard
+	for ix := x - radius; ix <= x+radius; ix++ {
+		if battleship.IsValid(ix, y) {
+			row.Set(ix, y)
+		}
+	}
+	return row.AndNot(a.known).Count()
+}
+
+// markSunk tries to work out which hits belong to the ship sunk at x,y, and marks them as sunk.
+// If there are more hits connected to x,y than the ship is large, we can't know which are part of the ship, and nothing is marked.
+func (a *AI) markSunk(x, y int, ship byte) {
+	class, ok := a.rules.GetFleet().Class(ship)
+	if !ok {
+		return
+	}
+
+	found := a.cluster(x, y)
+	if len(found) != len(class.Cells) {
+		return
+	}
+	for _, p := range found {
+		a.sunk.Set(p.X, p.Y)
+	}
+}
+
+// cluster returns the hits connected to x,y, that aren't known to be part of a sunk ship, starting with x,y.
+func (a *AI) cluster(x, y int) []battleship.Point {
+	var seen [battleship.BoardSize][battleship.BoardSize]bool
+	found := []battleship.Point{{X: x, Y: y}}
+	seen[x][y] = true
+	for i := 0; i < len(found); i++ {
+		p := found[i]
+		for _, next := range a.neighbours(p) {
+			if !battleship.IsValid(next.X, next.Y) || seen[next.X][next.Y] {
+				continue
+			}
+			seen[next.X][next.Y] = true
+			if a.board.PlayerHasHit(next.X, next.Y) && !a.sunk.Has(next.X, next.Y) {
+				found = append(found, next)
+			}
+		}
+	}
+	return found
+}
+
+// neighbours returns the points a ship at p could continue on to.
+// The points might not be on the board.
+func (a *AI) neighbours(p battleship.Point) []battleship.Point {
+	points := []battleship.Point{{X: p.X + 1, Y: p.Y}, {X: p.X - 1, Y: p.Y}, {X: p.X, Y: p.Y + 1}, {X: p.X, Y: p.Y - 1}}
+	if a.rules.Diagonal {
+		points = append(points, battleship.Point{X: p.X + 1, Y: p.Y + 1}, battleship.Point{X: p.X - 1, Y: p.Y - 1}, battleship.Point{X: p.X + 1, Y: p.Y - 1}, battleship.Point{X: p.X - 1, Y: p.Y + 1})
+	}
+	return points
+}
+
+// unknown returns true if the AI doesn't yet know what is at the given position.
+func (a *AI) unknown(x, y int) bool {
+	return !a.known.Has(x, y)
+}
+
+// markHalo marks all positions around the ship sunk at x,y as water.
+// It relies on ships not touching; every hit connected to x,y, orthogonally or diagonally, must be part of the sunk ship.
+func (a *AI) markHalo(x, y int) {
+	var seen [battleship.BoardSize][battleship.BoardSize]bool
+	stack := [][2]int{{x, y}}
+	seen[x][y] = true
+
+	for len(stack) > 0 {
+		x, y := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		for ix := x - 1; ix <= x+1; ix++ {
+			for iy := y - 1; iy <= y+1; iy++ {
+				if !battleship.IsValid(ix, iy) || seen[ix][iy] {
+					continue
+				}
+				seen[ix][iy] = true
+
+				if a.board.PlayerHasHit(ix, iy) {
+					// another part of the ship, search around it too.
+					stack = append(stack, [2]int{ix, iy})
+					continue
+				}
+				if !a.board.PlayerHasShot(ix, iy) {
+					a.board.PlayerMarkWater(ix, iy)
+				}
+			}
+		}
+	}
+}
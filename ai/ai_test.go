@@ -0,0 +1,197 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stewi1014/battleship"
+)
+
+// Testing of the AI is limited to checking for runtime errors.
+// Unless the AI actually crashes the program or enters an infinite loop,
+// and as long as the gameplay is enjoyable (tested), errors within the AI simply give the player an advantage.
+// Apart from that, there's no "correct" way to play the game either.
+
+// With this passing, we can say with a reasonably high degree of accuracy that the AI is stable.
+// For a game, this should suffice.
+func TestAI(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		rules battleship.Rules
+		games int
+	}{
+		{
+			desc:  "classic",
+			games: 10000,
+		},
+		{
+			desc:  "no touching",
+			rules: battleship.Rules{NoTouching: true},
+			games: 1000,
+		},
+		{
+			desc:  "bonus shot on hit",
+			rules: battleship.Rules{BonusShot: battleship.BonusOnHit},
+			games: 1000,
+		},
+		{
+			desc:  "bonus shot on sink",
+			rules: battleship.Rules{BonusShot: battleship.BonusOnSink},
+			games: 1000,
+		},
+		{
+			desc:  "advanced",
+			rules: battleship.Rules{Arsenal: battleship.AdvancedArsenal},
+			games: 1000,
+		},
+		{
+			desc:  "advanced no touching bonus shot",
+			rules: battleship.Rules{NoTouching: true, BonusShot: battleship.BonusOnHit, Arsenal: battleship.AdvancedArsenal},
+			games: 1000,
+		},
+		{
+			desc:  "diagonal",
+			rules: battleship.Rules{Diagonal: true},
+			games: 1000,
+		},
+		{
+			desc:  "diagonal no touching",
+			rules: battleship.Rules{Diagonal: true, NoTouching: true},
+			games: 1000,
+		},
+		{
+			desc:  "shapes",
+			rules: battleship.Rules{Fleet: battleship.ShapesFleet},
+			games: 1000,
+		},
+		{
+			desc:  "shapes no touching advanced",
+			rules: battleship.Rules{Fleet: battleship.ShapesFleet, NoTouching: true, Arsenal: battleship.AdvancedArsenal},
+			games: 1000,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			playAIGames(t, tC.rules, tC.games)
+		})
+	}
+}
+
+// playAIGames plays the given number of games between two AIs, failing if any game doesn't finish.
+func playAIGames(t *testing.T, rules battleship.Rules, tests int) {
+	maxTurns := battleship.BoardSize * battleship.BoardSize
+
+newGame:
+	for i := 0; i < tests; i++ {
+		ai1, ai2 := NewAI(rules), NewAI(rules) // boards are randomly generated
+		l1, l2 := battleship.NewLocalLink(ai1), battleship.NewLocalLink(ai2)
+
+		for turns := 0; ; turns++ {
+			won, err := ai1.Turn(l2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if won {
+				continue newGame
+			}
+
+			won, err = ai2.Turn(l1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if won {
+				continue newGame
+			}
+
+			if turns > maxTurns {
+				t.Fatalf("Maximum number of turns(%v) reached", maxTurns)
+				fmt.Println("ai1 board: \n", ai1.board)
+				fmt.Println("ai2 board: \n", ai2.board)
+			}
+		}
+	}
+}
+
+func TestAITrace(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		rules battleship.Rules
+	}{
+		{
+			desc: "classic",
+		},
+		{
+			desc:  "advanced",
+			rules: battleship.Rules{Arsenal: battleship.AdvancedArsenal},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			ai1, ai2 := NewAI(tC.rules), NewAI(tC.rules)
+			ai1.SetTrace(&buf)
+			ai2.SetTrace(&buf)
+
+			result, err := battleship.PlayGame(ai1, ai2, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if attacks := len(result.Moves[0]) + len(result.Moves[1]); len(lines) != attacks {
+				t.Errorf("got %v explanations for %v attacks", len(lines), attacks)
+			}
+			for _, line := range lines {
+				if !strings.Contains(line, "hunt mode") && !strings.Contains(line, "target mode, extending the hits at") {
+					t.Errorf("explanation %q doesn't give a mode", line)
+				}
+			}
+		})
+	}
+}
+
+func TestAIIncomingFire(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		rules battleship.Rules
+	}{
+		{
+			desc: "classic",
+		},
+		{
+			desc:  "advanced",
+			rules: battleship.Rules{Arsenal: battleship.AdvancedArsenal},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			ais := [2]*AI{NewAI(tC.rules), NewAI(tC.rules)}
+			ais[0].SetOutput(&buf)
+
+			result, err := battleship.PlayGame(ais[0], ais[1], nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			winner, loser := ais[result.Winner-1], ais[2-result.Winner]
+			if fleet := len(tC.rules.GetFleet()); loser.lost != fleet {
+				t.Errorf("loser counted %v ships lost, want all %v", loser.lost, fleet)
+			}
+			if winner.lost != loser.score {
+				t.Errorf("winner counted %v ships lost, but the loser sunk %v", winner.lost, loser.score)
+			}
+			var hit bool
+			for _, move := range result.Moves[1] {
+				if h, _ := battleship.Result(move.Shots); h {
+					hit = true
+				}
+			}
+			if hit && !strings.Contains(buf.String(), "!") {
+				t.Error("incoming hits weren't highlighted on the AI's board")
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stewi1014/battleship"
+)
+
+// AnalysisBudget is how long is spent analysing each shot after a game.
+const AnalysisBudget = 20 * time.Millisecond
+
+// blundersShown is how many of the worst blunders are listed in a report.
+const blundersShown = 5
+
+// blunderLoss is how much less likely to hit than the best shot a shot must be, to be called a blunder.
+const blunderLoss = 0.3
+
+// ShotReview is the analysis of a single plain shot.
+type ShotReview struct {
+	// Move is the index of the shot in the player's moves.
+	Move int
+	X, Y int
+	Hit  bool
+	// Chance is how likely the shot was to hit, given what the player knew, as estimated by a Sampler.
+	// The Sampler's layouts aren't weighted exactly by how likely they are, so it's a heuristic, not the exact chance.
+	Chance float64
+	// BestX, BestY is the shot most likely to hit, and Best the chance it would have.
+	BestX, BestY int
+	Best         float64
+
+	// Solved is true if the shot was made in an endgame small enough to solve exactly,
+	// in which case Expected is how many shots the game was expected to take to finish from it, and BestExpected the fewest possible.
+	// A shot on a position already known costs a shot, and leaves the game as it was.
+	Solved                 bool
+	Expected, BestExpected float64
+}
+
+// Blunder returns true if the shot was much less likely to hit than the best shot.
+func (r ShotReview) Blunder() bool {
+	return r.Best-r.Chance >= blunderLoss
+}
+
+// Analysis is a review of the shots a player made in a game.
+type Analysis struct {
+	Reviews []ShotReview
+	// Accuracy is how close the player's shots came to the best shots the Sampler found, as a percentage of their chances to hit.
+	Accuracy float64
+	// Luck is how many more hits the player made than their shots were expected to.
+	Luck float64
+	// EndgameLoss is how many more shots the player's endgame was expected to take than perfect play.
+	EndgameLoss float64
+}
+
+// Analyse replays the moves a player made in a game played by the given rules, and for each plain shot works out
+// the chance it would hit, given everything the player knew, and the chance the best shot would have.
+// The chances are estimated with a Sampler, spending up to budget on each shot; its sampling is a heuristic, not the exact distribution of layouts.
+// Endgames small enough are solved exactly, to find how many shots the player lost to imperfect play.
+// Special weapons aren't reviewed, but what they revealed is taken into account.
+func Analyse(rules battleship.Rules, moves []battleship.Move, budget time.Duration) Analysis {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	sampler := NewSampler(rules, rng)
+	board := rules.NewBoard()
+	sunk := make(map[byte]battleship.Point)
+	replay := battleship.NewReplayLink(moves)
+
+	var a Analysis
+	var chances, bests, hits float64
+	for i, move := range moves {
+		if move.Attack.Weapon == battleship.WeaponShot {
+			heat, samples := sampler.Heatmap(&board, sunk, budget)
+			if samples > 0 {
+				r := ShotReview{
+					Move:   i,
+					X:      move.Attack.X,
+					Y:      move.Attack.Y,
+					Hit:    move.Shots[0].Hit,
+					Chance: heat[move.Attack.X][move.Attack.Y],
+				}
+				r.BestX, r.BestY = HottestShot(&board, &heat, rng)
+				r.Best = heat[r.BestX][r.BestY]
+				if r.Chance > r.Best {
+					// the shot was on a position HottestShot wouldn't take; one known to be water.
+					r.Best = r.Chance
+				}
+				if e, ok := sampler.SolveEndgame(&board, sunk, solverLimit); ok {
+					r.Solved = true
+					r.Expected = e.Expected[r.X][r.Y]
+					r.BestExpected = e.Expected[e.Best.X][e.Best.Y]
+					if board.PlayerHasShot(r.X, r.Y) || board.PlayerKnowsWater(r.X, r.Y) {
+						// the solver has nothing for known positions; shooting one wastes a shot, then the game carries on as before.
+						r.Expected = 1 + r.BestExpected
+					}
+					a.EndgameLoss += r.Expected - r.BestExpected
+				}
+				a.Reviews = append(a.Reviews, r)
+
+				chances += r.Chance
+				bests += r.Best
+				if r.Hit {
+					hits++
+				}
+			}
+		}
+
+		shots, _ := move.Attack.Launch(&board, replay)
+		for _, shot := range shots {
+			if shot.Sunk != 0 {
+				sunk[shot.Sunk] = battleship.Point{X: shot.X, Y: shot.Y}
+			}
+		}
+	}
+
+	if bests > 0 {
+		a.Accuracy = 100 * chances / bests
+	}
+	a.Luck = hits - chances
+	return a
+}
+
+// Blunders returns the reviews of shots that were blunders.
+func (a Analysis) Blunders() (blunders []ShotReview) {
+	for _, r := range a.Reviews {
+		if r.Blunder() {
+			blunders = append(blunders, r)
+		}
+	}
+	return
+}
+
+// String returns a report of the analysis; accuracy, luck, and the worst blunders.
+func (a Analysis) String() string {
+	var sb strings.Builder
+	blunders := a.Blunders()
+	fmt.Fprintf(&sb, "Accuracy %.0f%%, luck %+.1f hits, %v blunders\n", a.Accuracy, a.Luck, len(blunders))
+	for _, r := range a.Reviews {
+		if r.Solved {
+			fmt.Fprintf(&sb, "  Endgame from move %v took %.1f more shots in total than perfect play, on average\n", r.Move+1, a.EndgameLoss)
+			break
+		}
+	}
+
+	sort.SliceStable(blunders, func(i, j int) bool {
+		return blunders[i].Best-blunders[i].Chance > blunders[j].Best-blunders[j].Chance
+	})
+	for i, r := range blunders {
+		if i == blundersShown {
+			fmt.Fprintf(&sb, "  and %v more\n", len(blunders)-blundersShown)
+			break
+		}
+		fmt.Fprintf(&sb, "  Move %v: %v had a %.0f%% chance to hit, %v had %.0f%%\n", r.Move+1, battleship.FormatPosition(r.X, r.Y), 100*r.Chance, battleship.FormatPosition(r.BestX, r.BestY), 100*r.Best)
+	}
+	return sb.String()
+}
@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stewi1014/battleship"
+)
+
+func TestAnalyse(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		rules battleship.Rules
+	}{
+		{
+			desc: "classic",
+		},
+		{
+			desc:  "no touching",
+			rules: battleship.Rules{NoTouching: true},
+		},
+		{
+			desc:  "advanced",
+			rules: battleship.Rules{Arsenal: battleship.AdvancedArsenal},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result, err := battleship.PlayGame(NewAI(tC.rules), NewAI(tC.rules), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			moves := result.Moves[result.Winner-1]
+			a := Analyse(tC.rules, moves, time.Millisecond)
+
+			var shots int
+			for _, move := range moves {
+				if move.Attack.Weapon == battleship.WeaponShot {
+					shots++
+				}
+			}
+			if len(a.Reviews) != shots {
+				t.Errorf("got %v reviews for %v shots", len(a.Reviews), shots)
+			}
+
+			for _, r := range a.Reviews {
+				if r.Chance < 0 || r.Chance > r.Best || r.Best > 1 {
+					t.Errorf("move %v has chance %v and best %v", r.Move, r.Chance, r.Best)
+				}
+				if r.Solved && r.Expected < r.BestExpected {
+					t.Errorf("move %v expected to finish in %v shots, fewer than the best %v", r.Move, r.Expected, r.BestExpected)
+				}
+				if at := moves[r.Move].Attack; at.X != r.X || at.Y != r.Y {
+					t.Errorf("move %v reviewed at %v, but was %v", r.Move, battleship.FormatPosition(r.X, r.Y), at)
+				}
+			}
+			if a.Accuracy <= 0 || a.Accuracy > 100 {
+				t.Errorf("got accuracy %v", a.Accuracy)
+			}
+		})
+	}
+}
+
+func TestAnalyseWastedShot(t *testing.T) {
+	// a patrol boat on a 3x3 board, at a3 and b3. A sonar ping at a1 finds nothing, and a1, known to be water, is shot anyway.
+	rules := battleship.Rules{Size: 3, Fleet: battleship.Fleet{battleship.SmallFleet[1]}, Arsenal: battleship.Arsenal{Sonars: 1}}
+	patrol := battleship.SmallFleet.Ships()[0]
+	moves := []battleship.Move{
+		{Attack: battleship.Attack{Weapon: battleship.WeaponSonar}},
+		{Attack: battleship.Attack{Weapon: battleship.WeaponShot}, Shots: []battleship.Shot{{}}},
+		{Attack: battleship.Attack{Weapon: battleship.WeaponShot, X: 2}, Shots: []battleship.Shot{{X: 2, Hit: true}}},
+		{Attack: battleship.Attack{Weapon: battleship.WeaponShot, X: 2, Y: 1}, Shots: []battleship.Shot{{X: 2, Y: 1, Hit: true, Sunk: patrol}}},
+	}
+	a := Analyse(rules, moves, time.Millisecond)
+
+	r := a.Reviews[0]
+	if r.Move != 1 || !r.Solved {
+		t.Fatalf("got review of move %v, solved %v", r.Move, r.Solved)
+	}
+	if r.Expected != 1+r.BestExpected {
+		t.Errorf("wasted shot expected to finish in %v shots, want one more than the best %v", r.Expected, r.BestExpected)
+	}
+	if a.EndgameLoss < 1 {
+		t.Errorf("got endgame loss %v, want at least the wasted shot", a.EndgameLoss)
+	}
+}
@@ -0,0 +1,165 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/stewi1014/battleship"
+)
+
+// Book is a set of openings; precomputed first shots to hunt with, for each set of rules.
+type Book struct {
+	Openings []Opening `json:"openings"`
+}
+
+// Opening is a sequence of hunting shots for games by a set of rules, each most likely to find a ship given that those before it missed.
+// The sequence is fixed, so an AI following it always opens with the same shots.
+type Opening struct {
+	Size int `json:"size"`
+	// Fleet holds each ship of the fleet, formatted with ShipClass.Format, so fleets with the same names but different ships don't share openings.
+	Fleet      []string `json:"fleet"`
+	NoTouching bool     `json:"noTouching"`
+	Diagonal   bool     `json:"diagonal"`
+
+	// Shots holds the shots, formatted with FormatPosition.
+	Shots []string `json:"shots"`
+	// Chances holds how likely each shot is to be the first to hit.
+	Chances []float64 `json:"chances"`
+}
+
+// LoadBook reads the book at path.
+// If there is no file yet, an empty book is returned.
+func LoadBook(path string) (*Book, error) {
+	b := &Book{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Save writes the book to a file.
+func (b *Book) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Lookup returns the opening shots for the given rules, or false if the book has none.
+func (b *Book) Lookup(rules battleship.Rules) ([]battleship.Point, bool) {
+	for _, o := range b.Openings {
+		if !o.fits(rules) {
+			continue
+		}
+		var shots []battleship.Point
+		for _, position := range o.Shots {
+			x, y, err := battleship.ParsePosition(position)
+			if err != nil {
+				return nil, false
+			}
+			shots = append(shots, battleship.Point{X: x, Y: y})
+		}
+		return shots, true
+	}
+	return nil, false
+}
+
+// Add adds an opening to the book, replacing any for the same rules.
+func (b *Book) Add(o Opening) {
+	for i := range b.Openings {
+		if b.Openings[i].sameRules(o) {
+			b.Openings[i] = o
+			return
+		}
+	}
+	b.Openings = append(b.Openings, o)
+}
+
+// fits returns true if the opening is for games by the given rules.
+func (o Opening) fits(rules battleship.Rules) bool {
+	return o.sameRules(newOpening(rules))
+}
+
+// sameRules returns true if both openings are for games by the same rules.
+func (o Opening) sameRules(p Opening) bool {
+	return o.Size == p.Size && strings.Join(o.Fleet, ",") == strings.Join(p.Fleet, ",") &&
+		o.NoTouching == p.NoTouching && o.Diagonal == p.Diagonal
+}
+
+// newOpening returns an opening with no shots, for the given rules.
+func newOpening(rules battleship.Rules) Opening {
+	o := Opening{
+		Size:       rules.GetSize(),
+		NoTouching: rules.NoTouching,
+		Diagonal:   rules.Diagonal,
+	}
+	for _, class := range rules.GetFleet() {
+		o.Fleet = append(o.Fleet, class.Format())
+	}
+	return o
+}
+
+// GenerateOpening simulates placing fleets the given number of times, as an AI of the given difficulty would,
+// and finds the sequence of up to length shots that finds the first ship soonest.
+// Each shot is the position with a ship in the most layouts that every shot before it missed.
+func GenerateOpening(rules battleship.Rules, difficulty, layouts, length int, rng *rand.Rand) (Opening, error) {
+	if layouts < 1 || length < 1 {
+		return Opening{}, errors.New("need at least one layout and shot")
+	}
+	boards := make([]battleship.Board, layouts)
+	for i := range boards {
+		boards[i] = battleship.PlacementBoard(rng, rules, difficulty, nil)
+	}
+
+	o := newOpening(rules)
+	var shot [battleship.BoardSize][battleship.BoardSize]bool
+	for len(o.Shots) < length && len(boards) > 0 {
+		var counts [battleship.BoardSize][battleship.BoardSize]int
+		for i := range boards {
+			for x := 0; x < battleship.BoardSize; x++ {
+				for y := 0; y < battleship.BoardSize; y++ {
+					if boards[i].ShipAt(x, y) > 0 {
+						counts[x][y]++
+					}
+				}
+			}
+		}
+
+		best, most := battleship.Point{}, 0
+		for x := 0; x < battleship.BoardSize; x++ {
+			for y := 0; y < battleship.BoardSize; y++ {
+				if rules.InBounds(x, y) && !shot[x][y] && counts[x][y] > most {
+					best, most = battleship.Point{X: x, Y: y}, counts[x][y]
+				}
+			}
+		}
+		if most == 0 {
+			break
+		}
+		shot[best.X][best.Y] = true
+		o.Shots = append(o.Shots, battleship.FormatPosition(best.X, best.Y))
+		o.Chances = append(o.Chances, float64(most)/float64(layouts))
+
+		// carry on with the layouts the shot would miss.
+		missed := boards[:0]
+		for _, b := range boards {
+			if b.ShipAt(best.X, best.Y) == 0 {
+				missed = append(missed, b)
+			}
+		}
+		boards = missed
+	}
+	return o, nil
+}
@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stewi1014/battleship"
+)
+
+func TestGenerateOpening(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		rules battleship.Rules
+	}{
+		{
+			desc: "classic",
+		},
+		{
+			desc:  "small board",
+			rules: battleship.Rules{Size: 6, Fleet: battleship.SmallFleet},
+		},
+		{
+			desc:  "shapes no touching",
+			rules: battleship.Rules{Fleet: battleship.ShapesFleet, NoTouching: true},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			o, err := GenerateOpening(tC.rules, battleship.DifficultyEasy, 1000, 8, rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(o.Shots) == 0 || len(o.Shots) > 8 || len(o.Chances) != len(o.Shots) {
+				t.Fatalf("got %v shots and %v chances", len(o.Shots), len(o.Chances))
+			}
+
+			var found float64
+			seen := make(map[string]bool)
+			for i, position := range o.Shots {
+				x, y, err := battleship.ParsePosition(position)
+				if err != nil || !tC.rules.InBounds(x, y) {
+					t.Errorf("shot %v is %v, off the board", i+1, position)
+				}
+				if seen[position] {
+					t.Errorf("shot %v at %v repeats an earlier shot", i+1, position)
+				}
+				seen[position] = true
+				found += o.Chances[i]
+			}
+			if found <= 0 || found > 1+1e-9 {
+				t.Errorf("opening finds a ship %v of the time", found)
+			}
+
+			book := &Book{}
+			book.Add(o)
+			book.Add(o)
+			if len(book.Openings) != 1 {
+				t.Errorf("book has %v openings after adding the same one twice", len(book.Openings))
+			}
+			shots, ok := book.Lookup(tC.rules)
+			if !ok || len(shots) != len(o.Shots) {
+				t.Fatalf("looked up %v shots, ok %v", len(shots), ok)
+			}
+			if _, ok := book.Lookup(battleship.Rules{Size: 4, Fleet: battleship.SmallFleet}); ok {
+				t.Error("found an opening for other rules")
+			}
+
+			ai := NewAI(tC.rules)
+			ai.opening = shots
+			if at, _ := ai.chooseAttack(); at.X != shots[0].X || at.Y != shots[0].Y {
+				t.Errorf("AI attacked %v, not the opening's first shot %v", at, o.Shots[0])
+			}
+		})
+	}
+}
+
+func TestBookSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "battleship")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "book.json")
+
+	book, err := LoadBook(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(book.Openings) != 0 {
+		t.Fatalf("new book has %v openings", len(book.Openings))
+	}
+	carrier := battleship.ShipClass{Name: "Carrier", Symbol: 'C', Cells: battleship.Line(5)}
+	book.Add(Opening{Size: 10, Fleet: []string{carrier.Format()}, Shots: []string{"e5", "f6"}, Chances: []float64{0.2, 0.1}})
+	if err := book.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadBook(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shots, ok := loaded.Lookup(battleship.Rules{Fleet: battleship.Fleet{carrier}})
+	if !ok || len(shots) != 2 {
+		t.Errorf("looked up %v after saving, ok %v", shots, ok)
+	}
+	carrier.Cells = battleship.Line(4)
+	if _, ok := loaded.Lookup(battleship.Rules{Fleet: battleship.Fleet{carrier}}); ok {
+		t.Error("found an opening for a fleet with the same name, but a different ship")
+	}
+}
@@ -0,0 +1,423 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/stewi1014/battleship"
+)
+
+// maxNashLayouts is the most fleet layouts a strategy can be computed over; the game on larger boards is too big to analyse.
+const maxNashLayouts = 20000
+
+// nashSmoothing is the weight spread evenly over every layout when shooting by a strategy,
+// so layouts the strategy has never seen placed are still searched for sensibly.
+const nashSmoothing = 0.01
+
+// Strategy is an approximate Nash equilibrium of the game on a small board, computed by ComputeStrategy with fictitious play.
+// Placing and shooting are treated as a game of their own; the placer wants the game to take as many shots as possible, and the shooter as few.
+type Strategy struct {
+	Size int `json:"size"`
+	// Fleet holds each ship of the fleet, formatted with ShipClass.Format, so a strategy isn't played with a different fleet of the same names.
+	Fleet      []string `json:"fleet"`
+	NoTouching bool     `json:"noTouching"`
+	Diagonal   bool     `json:"diagonal"`
+
+	// Layouts holds every layout of the fleet, as the positions of each ship, in fleet order.
+	Layouts [][]string `json:"layouts"`
+	// Choices holds the index of the layout the placer chose in each round of fictitious play.
+	// Placing by the strategy picks one of them at random.
+	// Shooting by the strategy picks a round at random, and shoots as if the layout was picked from the choices before it.
+	Choices []int `json:"choices"`
+
+	// PlacementValue is the average number of shots the greedy shooter takes to sink a fleet placed by the strategy.
+	// A true best response could take fewer, so it's only a heuristic upper bound on how long placing by the strategy holds out.
+	PlacementValue float64 `json:"placementValue"`
+	// ShootingValue is the most shots, on average, shooting by the strategy takes to sink any layout.
+	// The gap between it and PlacementValue suggests how close the strategy is to an equilibrium, but doesn't bound how exploitable it is.
+	ShootingValue float64 `json:"shootingValue"`
+}
+
+// ComputeStrategy computes an approximate Nash equilibrium for games played by the given rules, with the given number of rounds of fictitious play.
+// Each round, the shooter plays its best response to the placer's choices so far, and then the placer picks the layout that has held out longest on average.
+// The shooter's best response is approximated greedily, by always shooting the position with the most weight of possible layouts,
+// so the equilibrium is only approximate for the shooter; see PlacementValue.
+func ComputeStrategy(rules battleship.Rules, rounds int) (*Strategy, error) {
+	if rounds < 1 {
+		return nil, errors.New("need at least one round")
+	}
+	g, err := newNashGame(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Strategy{
+		Size:       rules.GetSize(),
+		NoTouching: rules.NoTouching,
+		Diagonal:   rules.Diagonal,
+	}
+	for _, class := range rules.GetFleet() {
+		s.Fleet = append(s.Fleet, class.Format())
+	}
+	for _, layout := range g.layouts {
+		var ships []string
+		for _, mask := range layout {
+			ships = append(ships, battleship.FormatPoints(mask.Points()))
+		}
+		s.Layouts = append(s.Layouts, ships)
+	}
+
+	counts := make([]float64, len(g.layouts))
+	total := make([]float64, len(g.layouts))
+	shots := make([]int, len(g.layouts))
+	for round := 0; round < rounds; round++ {
+		g.playAll(s.weights(counts, round), shots)
+
+		best := 0
+		for l := range total {
+			total[l] += float64(shots[l])
+			if total[l] > total[best] {
+				best = l
+			}
+		}
+		s.Choices = append(s.Choices, best)
+		counts[best]++
+	}
+
+	for l := range total {
+		if v := total[l] / float64(rounds); v > s.ShootingValue {
+			s.ShootingValue = v
+		}
+	}
+	g.playAll(s.weights(counts, rounds), shots)
+	for l, count := range counts {
+		s.PlacementValue += count / float64(rounds) * float64(shots[l])
+	}
+	return s, nil
+}
+
+// weights returns the weight of each layout when shooting as if it's picked from the choices before the given round,
+// being counted in counts.
+func (s *Strategy) weights(counts []float64, round int) []float64 {
+	weights := make([]float64, len(counts))
+	for l, count := range counts {
+		weights[l] = nashSmoothing / float64(len(counts))
+		if round > 0 {
+			weights[l] += count / float64(round)
+		}
+	}
+	return weights
+}
+
+// LoadStrategy reads a strategy saved with Save.
+func LoadStrategy(path string) (*Strategy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Strategy{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if len(s.Choices) == 0 {
+		return nil, fmt.Errorf("%v has no strategy in it", path)
+	}
+	for _, l := range s.Choices {
+		if l < 0 || l >= len(s.Layouts) {
+			return nil, fmt.Errorf("%v has a choice of layout %v, but only %v layouts", path, l, len(s.Layouts))
+		}
+	}
+	return s, nil
+}
+
+// Save writes the strategy to a file.
+func (s *Strategy) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Fits returns an error if the strategy wasn't computed for the given rules.
+func (s *Strategy) Fits(rules battleship.Rules) error {
+	var fleet []string
+	for _, class := range rules.GetFleet() {
+		fleet = append(fleet, class.Format())
+	}
+	switch {
+	case s.Size != rules.GetSize():
+		return fmt.Errorf("strategy is for a board of size %v", s.Size)
+	case strings.Join(s.Fleet, "\n") != strings.Join(fleet, "\n"):
+		return fmt.Errorf("strategy is for the fleet %v", strings.Join(s.Fleet, ", "))
+	case s.NoTouching != rules.NoTouching || s.Diagonal != rules.Diagonal:
+		return errors.New("strategy is for different placement rules")
+	}
+	return nil
+}
+
+// game returns the game over the strategy's layouts.
+func (s *Strategy) game() (*nashGame, error) {
+	g := &nashGame{}
+	for _, layout := range s.Layouts {
+		var masks []battleship.Bitboard
+		var occupied battleship.Bitboard
+		for _, positions := range layout {
+			var mask battleship.Bitboard
+			for _, position := range strings.Fields(positions) {
+				x, y, err := battleship.ParsePosition(position)
+				if err != nil {
+					return nil, err
+				}
+				mask.Set(x, y)
+			}
+			masks = append(masks, mask)
+			occupied = occupied.Or(mask)
+		}
+		g.layouts = append(g.layouts, masks)
+		g.occupied = append(g.occupied, occupied)
+	}
+	return g, nil
+}
+
+// nashGame is the game between a placer and a shooter, played over every layout of a fleet at once.
+type nashGame struct {
+	// layouts holds the positions of each ship, and occupied all the positions with ships, for each layout.
+	layouts  [][]battleship.Bitboard
+	occupied []battleship.Bitboard
+}
+
+// newNashGame enumerates every layout of the fleet.
+func newNashGame(rules battleship.Rules) (*nashGame, error) {
+	var masks, avoid [][]battleship.Bitboard
+	for _, ship := range rules.GetFleet().Ships() {
+		var shipMasks, shipAvoid []battleship.Bitboard
+		for _, cells := range rules.Placements(ship) {
+			mask := battleship.BitboardOf(cells)
+			shipMasks = append(shipMasks, mask)
+			if rules.NoTouching {
+				mask = mask.Halo()
+			}
+			shipAvoid = append(shipAvoid, mask)
+		}
+		masks = append(masks, shipMasks)
+		avoid = append(avoid, shipAvoid)
+	}
+
+	g := &nashGame{}
+	placed := make([]battleship.Bitboard, len(masks))
+	var place func(i int, occupied battleship.Bitboard) error
+	place = func(i int, occupied battleship.Bitboard) error {
+		if i == len(masks) {
+			if len(g.layouts) == maxNashLayouts {
+				return fmt.Errorf("more than %v layouts; try a smaller board or fleet", maxNashLayouts)
+			}
+			g.layouts = append(g.layouts, append([]battleship.Bitboard(nil), placed...))
+			g.occupied = append(g.occupied, occupied)
+			return nil
+		}
+		for j, mask := range masks[i] {
+			if !avoid[i][j].And(occupied).Empty() {
+				continue
+			}
+			placed[i] = mask
+			if err := place(i+1, occupied.Or(mask)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := place(0, battleship.Bitboard{}); err != nil {
+		return nil, err
+	}
+	if len(g.layouts) == 0 {
+		return nil, errors.New("the fleet doesn't fit on the board")
+	}
+	return g, nil
+}
+
+// playAll plays the shooter, shooting by the given layout weights, against every layout at once,
+// recording in shots how many shots each layout took to sink.
+func (g *nashGame) playAll(weights []float64, shots []int) {
+	all := make([]int, len(g.layouts))
+	for l := range all {
+		all[l] = l
+	}
+	g.play(weights, all, battleship.Bitboard{}, battleship.Bitboard{}, 0, shots)
+}
+
+// play continues playing against the given layouts, all consistent with the shots taken so far, recording in shots how many shots each takes to sink.
+func (g *nashGame) play(weights []float64, layouts []int, shot, hits battleship.Bitboard, depth int, shots []int) {
+	if g.occupied[layouts[0]].AndNot(hits).Empty() {
+		// all sunk
+		for _, l := range layouts {
+			shots[l] = depth
+		}
+		return
+	}
+
+	p := g.choose(weights, layouts, shot)
+	var at battleship.Bitboard
+	at.Set(p.X, p.Y)
+	shot = shot.Or(at)
+
+	// split the layouts by what the shot shows; a miss, a hit, or sinking a ship.
+	outcomes := make([][]int, len(g.layouts[0])+2)
+	for _, l := range layouts {
+		outcomes[g.outcome(l, hits, p)] = append(outcomes[g.outcome(l, hits, p)], l)
+	}
+	for outcome, ls := range outcomes {
+		if len(ls) == 0 {
+			continue
+		}
+		next := hits
+		if outcome > 0 {
+			next = hits.Or(at)
+		}
+		g.play(weights, ls, shot, next, depth+1, shots)
+	}
+}
+
+// outcome returns what shooting p shows against layout l, given the hits so far;
+// 0 for a miss, 1 for a hit, or 2 plus the index of the ship sunk.
+func (g *nashGame) outcome(l int, hits battleship.Bitboard, p battleship.Point) int {
+	if !g.occupied[l].Has(p.X, p.Y) {
+		return 0
+	}
+	var at battleship.Bitboard
+	at.Set(p.X, p.Y)
+	for i, mask := range g.layouts[l] {
+		if mask.Has(p.X, p.Y) {
+			if mask.AndNot(hits.Or(at)).Empty() {
+				return i + 2
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// choose returns the position not yet shot with the most weight of the given layouts having a ship there.
+// Ties go to the first position, so the shooter is predictable given its weights.
+func (g *nashGame) choose(weights []float64, layouts []int, shot battleship.Bitboard) (best battleship.Point) {
+	var heat [battleship.BoardSize][battleship.BoardSize]float64
+	for _, l := range layouts {
+		for _, p := range g.occupied[l].Points() {
+			heat[p.X][p.Y] += weights[l]
+		}
+	}
+	most := -1.0
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			if !shot.Has(x, y) && heat[x][y] > most {
+				best, most = battleship.Point{X: x, Y: y}, heat[x][y]
+			}
+		}
+	}
+	return
+}
+
+// NewStrategyAI returns an AI that plays by the given strategy, placing its ships and choosing how to shoot at random by it.
+func NewStrategyAI(rules battleship.Rules, strategy *Strategy) (*StrategyAI, error) {
+	if err := strategy.Fits(rules); err != nil {
+		return nil, err
+	}
+	g, err := strategy.game()
+	if err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	a := &StrategyAI{
+		board: rules.NewBoard(),
+		rules: rules,
+		game:  g,
+	}
+	layout := g.layouts[strategy.Choices[rng.Intn(len(strategy.Choices))]]
+	for i, ship := range rules.GetFleet().Ships() {
+		if err := a.board.PlaceCells(layout[i].Points(), ship); err != nil {
+			return nil, err
+		}
+	}
+
+	round := rng.Intn(len(strategy.Choices))
+	counts := make([]float64, len(g.layouts))
+	for _, l := range strategy.Choices[:round] {
+		counts[l]++
+	}
+	a.weights = strategy.weights(counts, round)
+	return a, nil
+}
+
+// StrategyAI is a battleship-playing AI that plays by a Strategy.
+// It only takes plain shots, never using special weapons.
+type StrategyAI struct {
+	board battleship.Board
+	score int
+	rules battleship.Rules
+
+	game    *nashGame
+	weights []float64
+	// shot and hits are the positions shot and hit, and sunk the ships sunk, as bits by index in the fleet.
+	shot, hits battleship.Bitboard
+	sunk       uint8
+
+	// the opponent's fire doesn't change how the strategy plays, but is kept as for the other AIs.
+	fireLog
+}
+
+// GetBoard implements Player.
+func (a *StrategyAI) GetBoard() *battleship.Board {
+	return &a.board
+}
+
+// Turn implements Player.
+func (a *StrategyAI) Turn(remote battleship.Link) (won bool, err error) {
+	a.incoming = nil
+	for {
+		var layouts []int
+		for l := range a.game.layouts {
+			if a.consistent(l) {
+				layouts = append(layouts, l)
+			}
+		}
+		if len(layouts) == 0 {
+			return false, errors.New("the opponent's fleet doesn't fit the strategy")
+		}
+
+		p := a.game.choose(a.weights, layouts, a.shot)
+		hit, sunk := remote.TakeShot(p.X, p.Y)
+		a.board.PlayerShot(p.X, p.Y, hit)
+		a.shot.Set(p.X, p.Y)
+		if hit {
+			a.hits.Set(p.X, p.Y)
+		}
+		if sunk != 0 {
+			a.score++
+			a.sunk |= 1 << (sunk>>5 - 1)
+		}
+
+		if a.rules.Won(a.score) || !a.rules.ShootAgain(hit, sunk) {
+			return a.rules.Won(a.score), nil
+		}
+	}
+}
+
+// consistent returns true if layout l fits everything the AI has seen.
+func (a *StrategyAI) consistent(l int) bool {
+	if a.game.occupied[l].And(a.shot) != a.hits {
+		return false
+	}
+	for i, mask := range a.game.layouts[l] {
+		sunk := a.sunk&(1<<uint(i)) > 0
+		if sunk != mask.AndNot(a.hits).Empty() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stewi1014/battleship"
+)
+
+func TestComputeStrategy(t *testing.T) {
+	patrol := battleship.Fleet{{Name: "Patrol Boat", Symbol: 'P', Cells: battleship.Line(2)}}
+	testCases := []struct {
+		desc    string
+		rules   battleship.Rules
+		layouts int
+		value   float64
+	}{
+		{
+			desc:    "patrol boat 2x2",
+			rules:   battleship.Rules{Fleet: patrol, Size: 2},
+			layouts: 4,
+			value:   3,
+		},
+		{
+			desc:    "small fleet 4x4",
+			rules:   battleship.Rules{Fleet: battleship.SmallFleet, Size: 4},
+			layouts: 264,
+		},
+		{
+			desc:    "no touching",
+			rules:   battleship.Rules{Fleet: battleship.SmallFleet, Size: 4, NoTouching: true},
+			layouts: 104,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s, err := ComputeStrategy(tC.rules, 50)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(s.Layouts) != tC.layouts {
+				t.Errorf("got %v layouts, want %v", len(s.Layouts), tC.layouts)
+			}
+			if s.ShootingValue < s.PlacementValue-1e-9 {
+				t.Errorf("shooting value %v is less than placement value %v", s.ShootingValue, s.PlacementValue)
+			}
+			if tC.value > 0 && (math.Abs(s.PlacementValue-tC.value) > 0.1 || math.Abs(s.ShootingValue-tC.value) > 0.1) {
+				t.Errorf("got values %v and %v, want %v", s.PlacementValue, s.ShootingValue, tC.value)
+			}
+
+			dir, err := ioutil.TempDir("", "battleship")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "strategy.json")
+			if err := s.Save(path); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadStrategy(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := battleship.PlayGame(mustStrategyAI(t, tC.rules, loaded), mustStrategyAI(t, tC.rules, loaded), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Moves[result.Winner-1]) > tC.rules.GetSize()*tC.rules.GetSize() {
+				t.Errorf("winner took %v shots", len(result.Moves[result.Winner-1]))
+			}
+		})
+	}
+
+	if _, err := ComputeStrategy(battleship.Rules{}, 1); err == nil {
+		t.Error("computed a strategy for the classic game")
+	}
+}
+
+func TestStrategyFits(t *testing.T) {
+	patrol := battleship.ShipClass{Name: "Patrol Boat", Symbol: 'P', Cells: battleship.Line(2)}
+	rules := battleship.Rules{Fleet: battleship.Fleet{patrol}, Size: 3}
+	s, err := ComputeStrategy(rules, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Fits(rules); err != nil {
+		t.Errorf("strategy doesn't fit the rules it was computed for; %v", err)
+	}
+
+	patrol.Cells = battleship.Line(3)
+	other := battleship.Rules{Fleet: battleship.Fleet{patrol}, Size: 3}
+	if err := s.Fits(other); err == nil {
+		t.Error("strategy fits a fleet with the same name, but a different ship")
+	}
+	if _, err := NewStrategyAI(other, s); err == nil {
+		t.Error("AI plays a strategy for a fleet with the same name, but a different ship")
+	}
+}
+
+func mustStrategyAI(t *testing.T, rules battleship.Rules, s *Strategy) *StrategyAI {
+	ai, err := NewStrategyAI(rules, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ai
+}
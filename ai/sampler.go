@@ -0,0 +1,522 @@
+package ai
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/stewi1014/battleship"
+)
+
+// NewSampler returns a new Sampler for games played by the given rules.
+func NewSampler(rules battleship.Rules, rng *rand.Rand) *Sampler {
+	s := &Sampler{
+		rules: rules,
+		rng:   rng,
+		ships: rules.GetFleet().Ships(),
+	}
+
+	for _, ship := range s.ships {
+		placements := rules.Placements(ship)
+		var byCell [battleship.BoardSize][battleship.BoardSize][]int
+		masks := make([]battleship.Bitboard, len(placements))
+		halos := make([]battleship.Bitboard, len(placements))
+		for i, cells := range placements {
+			for _, cell := range cells {
+				byCell[cell.X][cell.Y] = append(byCell[cell.X][cell.Y], i)
+			}
+			masks[i] = battleship.BitboardOf(cells)
+			halos[i] = masks[i].Halo()
+		}
+		s.placements = append(s.placements, placements)
+		s.byCell = append(s.byCell, byCell)
+		s.masks = append(s.masks, masks)
+		s.halos = append(s.halos, halos)
+	}
+
+	return s
+}
+
+// Sampler estimates where the opponent's ships are by sampling complete fleet layouts
+// consistent with everything a player has seen; their misses, hits, sonar contacts and the ships they've sunk.
+// Unlike following lines of hits, it copes with ships placed next to each other, and clusters of hits that could be any of several ships.
+type Sampler struct {
+	rules battleship.Rules
+	rng   *rand.Rand
+	ships []byte
+
+	// placements holds every placement of each ship, indexed the same as ships.
+	placements [][][]battleship.Point
+	// byCell holds the indexes of the placements covering each position, for each ship.
+	byCell [][battleship.BoardSize][battleship.BoardSize][]int
+	// masks and halos hold the positions covered by, and next to, each placement of each ship.
+	masks, halos [][]battleship.Bitboard
+}
+
+// maxSamples stops the Sampler from sampling any more layouts once it has a clear enough picture.
+const maxSamples = 10000
+
+// Heatmap samples fleet layouts consistent with what the player knows, until budget runs out,
+// returning how often each position was occupied by a ship across the samples, and the number of samples taken.
+// b is the player's board, and sunk holds the position of the shot that sunk each of the opponent's sunk ships.
+// If no consistent layouts are found, samples is 0.
+func (s *Sampler) Heatmap(b *battleship.Board, sunk map[byte]battleship.Point, budget time.Duration) (heat [battleship.BoardSize][battleship.BoardSize]float64, samples int) {
+	sl := s.newSampling(b, sunk)
+	deadline := time.Now().Add(budget)
+
+	for tries := 0; samples < maxSamples; tries++ {
+		// checking the time is slow compared to a sample, so only do it every so often.
+		if tries%64 == 0 && time.Now().After(deadline) {
+			break
+		}
+
+		if !sl.sample() {
+			continue
+		}
+		samples++
+		for x := 0; x < battleship.BoardSize; x++ {
+			for y := 0; y < battleship.BoardSize; y++ {
+				if sl.occupied.Has(x, y) {
+					heat[x][y]++
+				}
+			}
+		}
+	}
+
+	if samples > 0 {
+		for x := 0; x < battleship.BoardSize; x++ {
+			for y := 0; y < battleship.BoardSize; y++ {
+				heat[x][y] /= float64(samples)
+			}
+		}
+	}
+	return
+}
+
+// sampling holds the state for sampling layouts consistent with a single board.
+type sampling struct {
+	*Sampler
+	b *battleship.Board
+
+	// candidates holds the indexes of the placements each ship could be in,
+	// ignoring where the other ships are, and isCandidate marks them by index.
+	candidates  [][]int
+	isCandidate [][]bool
+	hits        []battleship.Point
+	// sunk is true for ships that have been sunk, and must be placed over hits.
+	sunk []bool
+	// contacts holds the areas sonar pings found ships in, which layouts must each have a ship in; see contactAreas.
+	contacts []battleship.Bitboard
+
+	// state of the current sample
+	occupied battleship.Bitboard
+	placed   []bool
+}
+
+// newSampling works out where each ship could be, given what the player knows.
+func (s *Sampler) newSampling(b *battleship.Board, sunk map[byte]battleship.Point) *sampling {
+	sl := &sampling{
+		Sampler:     s,
+		b:           b,
+		candidates:  make([][]int, len(s.ships)),
+		isCandidate: make([][]bool, len(s.ships)),
+		sunk:        make([]bool, len(s.ships)),
+		placed:      make([]bool, len(s.ships)),
+		contacts:    contactAreas(b),
+	}
+
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			if b.PlayerHasHit(x, y) {
+				sl.hits = append(sl.hits, battleship.Point{X: x, Y: y})
+			}
+		}
+	}
+
+	for i, ship := range s.ships {
+		at, isSunk := sunk[ship]
+		sl.sunk[i] = isSunk
+		sl.isCandidate[i] = make([]bool, len(s.placements[i]))
+
+	placements:
+		for j, cells := range s.placements[i] {
+			var allHit, covers = true, false
+			for _, cell := range cells {
+				if b.PlayerKnowsWater(cell.X, cell.Y) || b.PlayerHasShot(cell.X, cell.Y) && !b.PlayerHasHit(cell.X, cell.Y) {
+					// we know there's no ship here
+					continue placements
+				}
+				allHit = allHit && b.PlayerHasHit(cell.X, cell.Y)
+				covers = covers || cell == at
+			}
+
+			// a sunk ship must be where it was sunk, and have been hit everywhere.
+			// a ship that's been hit everywhere would have been sunk.
+			if isSunk && (!covers || !allHit) || !isSunk && allHit {
+				continue
+			}
+			sl.candidates[i] = append(sl.candidates[i], j)
+			sl.isCandidate[i][j] = true
+		}
+	}
+
+	return sl
+}
+
+// contactAreas returns the areas of b that sonar pings found ships in, and that no hit already explains.
+// The board only marks the positions around each contact, not where the ping was centred,
+// so each contact's area is the positions around every centre a ping could have been at to mark it.
+// A ping marks every position in its area that wasn't already shot or known to be water,
+// so could have been at any centre whose area has only those.
+func contactAreas(b *battleship.Board) (areas []battleship.Bitboard) {
+	var contacts, known, hits battleship.Bitboard
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			if b.PlayerHasContact(x, y) {
+				contacts.Set(x, y)
+			}
+			if b.PlayerHasShot(x, y) || b.PlayerKnowsWater(x, y) {
+				known.Set(x, y)
+			}
+			if b.PlayerHasHit(x, y) {
+				hits.Set(x, y)
+			}
+		}
+	}
+	if contacts.Empty() {
+		return nil
+	}
+
+	var pings []battleship.Bitboard
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			var area battleship.Bitboard
+			for ix := x - battleship.SonarRadius; ix <= x+battleship.SonarRadius; ix++ {
+				for iy := y - battleship.SonarRadius; iy <= y+battleship.SonarRadius; iy++ {
+					if battleship.IsValid(ix, iy) {
+						area.Set(ix, iy)
+					}
+				}
+			}
+			if area.AndNot(contacts.Or(known)).Empty() && !area.And(contacts).Empty() {
+				pings = append(pings, area)
+			}
+		}
+	}
+
+	seen := make(map[battleship.Bitboard]bool)
+	for _, contact := range contacts.Points() {
+		var area battleship.Bitboard
+		for _, ping := range pings {
+			if ping.Has(contact.X, contact.Y) {
+				area = area.Or(ping)
+			}
+		}
+		if !area.And(hits).Empty() || seen[area] {
+			continue
+		}
+		seen[area] = true
+		areas = append(areas, area)
+	}
+	return
+}
+
+// hasContacts returns true if the layout in occupied has a ship in every contact area.
+func (sl *sampling) hasContacts(occupied battleship.Bitboard) bool {
+	for _, area := range sl.contacts {
+		if area.And(occupied).Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// sample builds a random layout in occupied, returning false if it couldn't find one consistent with what the player knows.
+// Layouts without a ship in every sonar contact's area are thrown away.
+// Sunk ships are placed first, then ships are placed to cover any hits not yet explained, and then the remaining ships placed anywhere they fit.
+func (sl *sampling) sample() bool {
+	sl.occupied = battleship.Bitboard{}
+	for i := range sl.placed {
+		sl.placed[i] = false
+	}
+
+	for i := range sl.ships {
+		if sl.sunk[i] && !sl.placeRandom(i, sl.candidates[i]) {
+			return false
+		}
+	}
+
+	for _, hit := range sl.hits {
+		if sl.occupied.Has(hit.X, hit.Y) {
+			continue
+		}
+
+		// pick a random unplaced ship to cover the hit, weighted by the number of ways it can.
+		var total int
+		options := make([][]int, len(sl.ships))
+		for i := range sl.ships {
+			if sl.placed[i] || sl.sunk[i] {
+				continue
+			}
+			for _, j := range sl.byCell[i][hit.X][hit.Y] {
+				if sl.isCandidate[i][j] && sl.fits(i, j) {
+					options[i] = append(options[i], j)
+				}
+			}
+			total += len(options[i])
+		}
+		if total == 0 {
+			return false
+		}
+
+		n := sl.rng.Intn(total)
+		for i := range options {
+			if n < len(options[i]) {
+				sl.place(i, options[i][n])
+				break
+			}
+			n -= len(options[i])
+		}
+	}
+
+	// remaining ships in random order, so the order doesn't favour any ship.
+	for _, i := range sl.rng.Perm(len(sl.ships)) {
+		if !sl.placed[i] && !sl.placeRandom(i, sl.candidates[i]) {
+			return false
+		}
+	}
+
+	return sl.hasContacts(sl.occupied)
+}
+
+// placeRandom places ship i at a random one of the given placements that fits, returning false if none do.
+func (sl *sampling) placeRandom(i int, placements []int) bool {
+	// try a few at random before checking them all, as most usually fit.
+	for tries := 0; tries < 8 && len(placements) > 0; tries++ {
+		j := placements[sl.rng.Intn(len(placements))]
+		if sl.fits(i, j) {
+			sl.place(i, j)
+			return true
+		}
+	}
+
+	var fitting []int
+	for _, j := range placements {
+		if sl.fits(i, j) {
+			fitting = append(fitting, j)
+		}
+	}
+	if len(fitting) == 0 {
+		return false
+	}
+	sl.place(i, fitting[sl.rng.Intn(len(fitting))])
+	return true
+}
+
+// fits returns true if ship i can go at placement j without overlapping, or touching if the rules forbid it, the ships already placed.
+func (sl *sampling) fits(i, j int) bool {
+	if sl.rules.NoTouching {
+		return sl.halos[i][j].And(sl.occupied).Empty()
+	}
+	return sl.masks[i][j].And(sl.occupied).Empty()
+}
+
+// place puts ship i at placement j.
+func (sl *sampling) place(i, j int) {
+	sl.occupied = sl.occupied.Or(sl.masks[i][j])
+	sl.placed[i] = true
+}
+
+// NewSamplerAI returns a new SamplerAI playing by the given rules, with randomly placed ships,
+// spending up to budget sampling layouts each shot.
+// It panics if the rules can't be played by; see Rules.Check.
+func NewSamplerAI(rules battleship.Rules, budget time.Duration) *SamplerAI {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return &SamplerAI{
+		rng:     rng,
+		rules:   rules,
+		board:   battleship.RandomBoard(rng, rules),
+		sunk:    make(map[byte]battleship.Point),
+		sampler: NewSampler(rules, rng),
+		budget:  budget,
+	}
+}
+
+// NewLearningAI returns a SamplerAI that learns where the named opponent likes to place their ships, and where they like to shoot.
+// Its targeting is weighted by the opponent's past layouts kept in history,
+// and once the game is over the opponent's layout is added and the history saved.
+func NewLearningAI(rules battleship.Rules, budget time.Duration, history *battleship.History, opponent string) *SamplerAI {
+	a := NewSamplerAI(rules, budget)
+	a.history = history
+	a.opponent = history.Opponent(opponent, rules)
+	prior := a.opponent.Prior()
+	a.prior = &prior
+	return a
+}
+
+// Opponent returns what a learning AI knows about its opponent, or nil if it isn't learning.
+func (a *SamplerAI) Opponent() *battleship.OpponentHistory {
+	return a.opponent
+}
+
+// SetDifficulty places the AI's ships as it would for the given difficulty, avoiding where a learning AI's opponent likes to shoot,
+// and on hard, has it solve endgames exactly.
+func (a *SamplerAI) SetDifficulty(difficulty int) {
+	a.board = battleship.PlacementBoard(a.rng, a.rules, difficulty, a.opponent)
+	a.endgame = difficulty == battleship.DifficultyHard
+}
+
+// SetOutput sets where the AI shows its board after each turn; nil, the default, shows nothing.
+func (a *SamplerAI) SetOutput(output io.Writer) {
+	a.output = output
+}
+
+// SetTrace sets where the AI explains each attack it makes; nil, the default, explains nothing.
+func (a *SamplerAI) SetTrace(trace io.Writer) {
+	a.traceOutput = trace
+}
+
+// SamplerAI is a battleship-playing AI that shoots wherever a Sampler finds ships most often.
+// It only takes plain shots, never using special weapons.
+type SamplerAI struct {
+	board battleship.Board
+	score int
+	rules battleship.Rules
+
+	// sunk holds where each of the opponent's sunk ships was sunk.
+	sunk    map[byte]battleship.Point
+	sampler *Sampler
+	budget  time.Duration
+	// endgame has the AI solve endgames exactly, when there are few enough layouts left.
+	endgame bool
+
+	// if learning about the opponent, prior weights positions by how often they've had ships there before.
+	history  *battleship.History
+	opponent *battleship.OpponentHistory
+	prior    *[battleship.BoardSize][battleship.BoardSize]float64
+
+	// output is shown the AI's board after each turn, and traceOutput the reason for each attack.
+	output, traceOutput io.Writer
+	fireLog
+
+	rng *rand.Rand
+}
+
+// GetBoard implements Player.
+func (a *SamplerAI) GetBoard() *battleship.Board {
+	return &a.board
+}
+
+// Turn implements Player.
+func (a *SamplerAI) Turn(remote battleship.Link) (won bool, err error) {
+	var streak int
+
+	// show our board after we return
+	defer func() {
+		a.showBoard(a.output, &a.board, a.rules.GetFleet(), streak)
+	}()
+
+	for {
+		x, y := a.chooseShot()
+		hit, sunk := remote.TakeShot(x, y)
+		a.board.PlayerShot(x, y, hit)
+		if sunk != 0 {
+			a.score++
+			a.sunk[sunk] = battleship.Point{X: x, Y: y}
+		}
+
+		if a.rules.Won(a.score) || !a.rules.ShootAgain(hit, sunk) {
+			return a.rules.Won(a.score), nil
+		}
+		streak++
+	}
+}
+
+// chooseShot picks the position ships were most often found in, that hasn't been shot yet,
+// or in the endgame, if solving endgames, the shot expected to finish the game soonest.
+func (a *SamplerAI) chooseShot() (x, y int) {
+	if a.endgame {
+		if e, ok := a.sampler.SolveEndgame(&a.board, a.sunk, solverLimit); ok {
+			trace(a.traceOutput, "Sampler AI attacks %v; endgame, expecting to finish in %.1f shots over %v possible layouts", battleship.FormatPosition(e.Best.X, e.Best.Y), e.Expected[e.Best.X][e.Best.Y], e.Layouts)
+			return e.Best.X, e.Best.Y
+		}
+	}
+
+	heat, samples := a.sampler.Heatmap(&a.board, a.sunk, a.budget)
+	weighted := heat
+	if a.prior != nil {
+		for ix := 0; ix < battleship.BoardSize; ix++ {
+			for iy := 0; iy < battleship.BoardSize; iy++ {
+				weighted[ix][iy] *= a.prior[ix][iy]
+			}
+		}
+	}
+
+	x, y = HottestShot(&a.board, &weighted, a.rng)
+	if a.traceOutput != nil {
+		reason := fmt.Sprintf("%v, ships were there in %.0f%% of %v sampled layouts", a.mode(), 100*heat[x][y], samples)
+		if a.prior != nil {
+			reason += ", weighted by the opponent's past layouts"
+		}
+		trace(a.traceOutput, "Sampler AI attacks %v; %v", battleship.FormatPosition(x, y), reason)
+		trace(a.traceOutput, "%v", a.board.FormatHeatmap(&heat))
+	}
+	return
+}
+
+// mode returns "target mode" if there are hits on ships that haven't been sunk yet, otherwise "hunt mode".
+func (a *SamplerAI) mode() string {
+	var unsunk int
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			if a.board.PlayerHasHit(x, y) {
+				unsunk++
+			}
+		}
+	}
+	fleet := a.rules.GetFleet()
+	for ship := range a.sunk {
+		class, _ := fleet.Class(ship)
+		unsunk -= len(class.Cells)
+	}
+
+	if unsunk > 0 {
+		return "target mode"
+	}
+	return "hunt mode"
+}
+
+// HottestShot returns the position with the highest heat that hasn't been shot yet, or isn't known to be water, on the given board.
+// Ties are broken randomly, so players using it aren't predictable when they have nothing to go on.
+func HottestShot(b *battleship.Board, heat *[battleship.BoardSize][battleship.BoardSize]float64, rng *rand.Rand) (x, y int) {
+	best, ties := -1.0, 0
+	for ix := 0; ix < battleship.BoardSize; ix++ {
+		for iy := 0; iy < battleship.BoardSize; iy++ {
+			if b.PlayerHasShot(ix, iy) || b.PlayerKnowsWater(ix, iy) {
+				continue
+			}
+			switch {
+			case heat[ix][iy] > best:
+				x, y, best, ties = ix, iy, heat[ix][iy], 1
+			case heat[ix][iy] == best:
+				ties++
+				if rng.Intn(ties) == 0 {
+					x, y = ix, iy
+				}
+			}
+		}
+	}
+	return
+}
+
+// GameOver implements GameEnder.
+// A learning AI records the opponent's layout and where they shot, and saves its history.
+func (a *SamplerAI) GameOver(won bool, opponent *battleship.Board) error {
+	if a.opponent == nil {
+		return nil
+	}
+	a.opponent.AddLayout(opponent)
+	a.opponent.AddShots(&a.board)
+	return a.history.Save()
+}
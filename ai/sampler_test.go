@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stewi1014/battleship"
+)
+
+func TestSamplerHeatmap(t *testing.T) {
+	rules := battleship.Rules{Fleet: battleship.Fleet{battleship.ClassicFleet[4]}} // a lone patrol boat
+	sampler := NewSampler(rules, rand.New(rand.NewSource(1)))
+
+	// everywhere has been missed, except a1, a2 and b1.
+	var b battleship.Board
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			b.PlayerShot(x, y, false)
+		}
+	}
+	b[0][0], b[1][0], b[0][1] = 0, 0, 0
+
+	heat, samples := sampler.Heatmap(&b, nil, time.Second)
+	if samples == 0 {
+		t.Fatal("no samples found")
+	}
+	if heat[0][0] != 1 {
+		t.Fatalf("a1 must always be occupied, got %v", heat[0][0])
+	}
+	if heat[1][0]+heat[0][1] != 1 {
+		t.Fatalf("only one of a2 and b1 can be occupied, got %v and %v", heat[1][0], heat[0][1])
+	}
+
+	// a hit at b1 leaves only one way for the ship to be placed.
+	b.PlayerShot(0, 1, true)
+	heat, _ = sampler.Heatmap(&b, nil, time.Second)
+	if heat[1][0] != 0 {
+		t.Fatalf("a2 can't be occupied after a hit at b1, got %v", heat[1][0])
+	}
+}
+
+func TestSamplerContacts(t *testing.T) {
+	sampler := NewSampler(battleship.Rules{}, rand.New(rand.NewSource(1)))
+
+	// a contact in the corner means a ship covers at least one of a1, a2, b1 and b2.
+	var b battleship.Board
+	b.PlayerSonar(0, 0, true)
+	heat, samples := sampler.Heatmap(&b, nil, time.Second)
+	if samples == 0 {
+		t.Fatal("no samples found")
+	}
+	if covered := heat[0][0] + heat[1][0] + heat[0][1] + heat[1][1]; covered < 1-1e-9 {
+		t.Errorf("got %v of a1, a2, b1 and b2 covered on average, want at least 1", covered)
+	}
+
+	// whatever shots and pings are made, the real layout still fits what's known.
+	rng := rand.New(rand.NewSource(1))
+	for game := 0; game < 200; game++ {
+		opponent := battleship.RandomBoard(rng, battleship.Rules{})
+		var b battleship.Board
+		for i := 0; i < 20; i++ {
+			x, y := rng.Intn(battleship.BoardSize), rng.Intn(battleship.BoardSize)
+			if rng.Intn(2) == 0 {
+				b.PlayerSonar(x, y, opponent.OpponentSonar(x, y))
+			} else if !b.PlayerHasShot(x, y) {
+				hit, _ := opponent.OpponentShot(x, y)
+				b.PlayerShot(x, y, hit)
+			}
+		}
+
+		var occupied battleship.Bitboard
+		for x := 0; x < battleship.BoardSize; x++ {
+			for y := 0; y < battleship.BoardSize; y++ {
+				if opponent.ShipAt(x, y) != 0 {
+					occupied.Set(x, y)
+				}
+			}
+		}
+		if !sampler.newSampling(&b, nil).hasContacts(occupied) {
+			t.Fatalf("the real layout doesn't fit the sonar contacts\n%v\n%v", opponent, b)
+		}
+	}
+}
+
+func TestSamplerAI(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		rules battleship.Rules
+	}{
+		{
+			desc: "classic",
+		},
+		{
+			desc:  "shapes no touching",
+			rules: battleship.Rules{Fleet: battleship.ShapesFleet, NoTouching: true},
+		},
+		{
+			desc:  "diagonal bonus shot",
+			rules: battleship.Rules{Diagonal: true, BonusShot: battleship.BonusOnHit},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				sampler := NewSamplerAI(tC.rules, time.Millisecond)
+				ai := NewAI(tC.rules)
+				l1, l2 := battleship.NewLocalLink(sampler), battleship.NewLocalLink(ai)
+
+				for turns := 0; ; turns++ {
+					if won, _ := sampler.Turn(l2); won {
+						break
+					}
+					if won, _ := ai.Turn(l1); won {
+						break
+					}
+					if turns > battleship.BoardSize*battleship.BoardSize {
+						t.Fatalf("Maximum number of turns reached\n%v", sampler.board.Format(tC.rules.GetFleet()))
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSample(b *testing.B) {
+	for _, tC := range []struct {
+		desc  string
+		rules battleship.Rules
+	}{
+		{"classic", battleship.Rules{}},
+		{"no touching", battleship.Rules{NoTouching: true}},
+	} {
+		s := NewSampler(tC.rules, rand.New(rand.NewSource(1)))
+		board := tC.rules.NewBoard()
+		sl := s.newSampling(&board, nil)
+		b.Run(tC.desc, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sl.sample()
+			}
+		})
+
+		// fits is most of the work of sampling; compare it with the same check on a byte-per-position board.
+		sl.sample()
+		var occupied battleship.Board
+		for _, p := range sl.occupied.Points() {
+			occupied[p.X][p.Y] = 1
+		}
+		b.Run(tC.desc+" fits", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for ship := range sl.placements {
+					for j := range sl.placements[ship] {
+						sl.fits(ship, j)
+					}
+				}
+			}
+		})
+		b.Run(tC.desc+" fits board", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for ship := range sl.placements {
+					for _, cells := range sl.placements[ship] {
+						boardFits(&occupied, cells, tC.rules.NoTouching)
+					}
+				}
+			}
+		})
+	}
+}
+
+// boardFits is sampling.fits as it was before bitboards, checking the positions of occupied that aren't zero.
+func boardFits(occupied *battleship.Board, cells []battleship.Point, noTouching bool) bool {
+	for _, cell := range cells {
+		if occupied[cell.X][cell.Y] != 0 {
+			return false
+		}
+		if noTouching {
+			for ix := cell.X - 1; ix <= cell.X+1; ix++ {
+				for iy := cell.Y - 1; iy <= cell.Y+1; iy++ {
+					if battleship.IsValid(ix, iy) && occupied[ix][iy] != 0 {
+						return false
+					}
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,307 @@
+package ai
+
+import (
+	"math"
+	"sort"
+
+	"github.com/stewi1014/battleship"
+)
+
+// solverLimit bounds the work SolveEndgame does, as the number of placements and shots it considers,
+// so it gives up quickly on positions that aren't endgames.
+const solverLimit = 20000
+
+// solverLayouts is the most layouts SolveEndgame will search over, as each one multiplies the work to do.
+const solverLayouts = 1000
+
+// Endgame is the exact solution of an endgame.
+type Endgame struct {
+	// Expected holds the expected number of shots to finish the game, when first shooting each position and playing perfectly after.
+	// It is 0 for positions that have been shot.
+	Expected [battleship.BoardSize][battleship.BoardSize]float64
+	// Best is the position to shoot, with the lowest Expected.
+	Best battleship.Point
+	// Layouts is the number of fleet layouts that fit what the player knows.
+	Layouts int
+}
+
+// SolveEndgame enumerates every fleet layout consistent with what the player knows, and searches for the shots that finish the game
+// in the fewest shots on average, assuming every layout is as likely.
+// b and sunk are as for Heatmap. If the search would take more than limit steps it gives up, returning false.
+func (s *Sampler) SolveEndgame(b *battleship.Board, sunk map[byte]battleship.Point, limit int) (Endgame, bool) {
+	return s.solveEndgame(b, sunk, limit, make(map[solverKey]float64))
+}
+
+// solveEndgame is SolveEndgame, remembering the positions it has solved in memo.
+// A nil memo searches every position afresh.
+func (s *Sampler) solveEndgame(b *battleship.Board, sunk map[byte]battleship.Point, limit int, memo map[solverKey]float64) (Endgame, bool) {
+	sl := s.newSampling(b, sunk)
+	sv := &solving{
+		sampling: sl,
+		limit:    limit,
+		memo:     memo,
+	}
+
+	var start solverState
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			if b.PlayerHasShot(x, y) || b.PlayerKnowsWater(x, y) {
+				start.shots.Set(x, y)
+			}
+			if b.PlayerHasHit(x, y) {
+				start.hits.Set(x, y)
+			}
+		}
+	}
+	for i, ship := range s.ships {
+		if _, ok := sunk[ship]; ok {
+			start.sunk |= 1 << uint(i)
+		}
+	}
+
+	if !sv.enumerate(0, make([]int, len(s.ships)), battleship.Bitboard{}, start.hits) || len(sv.layouts) == 0 {
+		return Endgame{}, false
+	}
+
+	var e Endgame
+	e.Layouts = len(sv.layouts)
+	all := make([]int, len(sv.layouts))
+	for i := range all {
+		all[i] = i
+	}
+	worth := sv.worth(all, start)
+	best := math.Inf(1)
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			if !worth.Has(x, y) {
+				continue
+			}
+			expected, ok := sv.shoot(all, start, x, y, math.Inf(1))
+			if !ok {
+				return Endgame{}, false
+			}
+			e.Expected[x][y] = expected
+			if expected < best {
+				best, e.Best = expected, battleship.Point{X: x, Y: y}
+			}
+		}
+	}
+	if math.IsInf(best, 1) {
+		// nothing left to shoot; the game is over.
+		return Endgame{}, false
+	}
+
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			if !start.shots.Has(x, y) && !worth.Has(x, y) {
+				// shooting where no layout has a ship shows nothing, wasting a shot.
+				e.Expected[x][y] = 1 + best
+			}
+		}
+	}
+	return e, true
+}
+
+// solverState is what the player knows at a point in the search;
+// the positions shot, which of them were hits, and the ships sunk, as bits by index in the fleet.
+type solverState struct {
+	shots, hits battleship.Bitboard
+	sunk        uint8
+}
+
+// solverKey identifies a position in the search.
+// What's known doesn't decide which layouts are left, as the order of the shots can decide where a ship was sunk,
+// so the layouts are part of the key too.
+type solverKey struct {
+	state   solverState
+	layouts string
+}
+
+// newSolverKey returns the key for the given layouts, which are in increasing order, and state.
+func newSolverKey(layouts []int, state solverState) solverKey {
+	buf := make([]byte, 0, len(layouts)*2)
+	for _, l := range layouts {
+		buf = append(buf, byte(l), byte(l>>8))
+	}
+	return solverKey{
+		state:   state,
+		layouts: string(buf),
+	}
+}
+
+// solving holds the state for solving a single endgame.
+type solving struct {
+	*sampling
+
+	// layouts holds the placement of each ship, for each consistent layout, and occupied the positions each layout has ships on.
+	layouts  [][]int
+	occupied []battleship.Bitboard
+	steps    int
+	limit    int
+	memo     map[solverKey]float64
+}
+
+// enumerate finds the consistent layouts, placing ships from i onwards, over the positions in occupied.
+// It returns false if the limit, or solverLayouts, was reached.
+func (sv *solving) enumerate(i int, placed []int, occupied, hits battleship.Bitboard) bool {
+	sv.steps++
+	if sv.steps > sv.limit {
+		return false
+	}
+
+	if i == len(placed) {
+		if hits.AndNot(occupied).Empty() && sv.hasContacts(occupied) {
+			// every hit and sonar contact is explained.
+			sv.layouts = append(sv.layouts, append([]int(nil), placed...))
+			sv.occupied = append(sv.occupied, occupied)
+		}
+		return len(sv.layouts) <= solverLayouts
+	}
+
+	for _, j := range sv.candidates[i] {
+		avoid := sv.masks[i][j]
+		if sv.rules.NoTouching {
+			avoid = sv.halos[i][j]
+		}
+		if !avoid.And(occupied).Empty() {
+			continue
+		}
+		placed[i] = j
+		if !sv.enumerate(i+1, placed, occupied.Or(sv.masks[i][j]), hits) {
+			return false
+		}
+	}
+	return true
+}
+
+// expected returns the expected number of shots to finish the game, playing perfectly, given the layouts it could be and what's known.
+func (sv *solving) expected(layouts []int, state solverState) (float64, bool) {
+	if state.sunk == 1<<uint(len(sv.ships))-1 {
+		return 0, true
+	}
+	if len(layouts) == 1 {
+		// we know where everything is; just shoot it.
+		return float64(sv.occupied[layouts[0]].AndNot(state.hits).Count()), true
+	}
+	key := newSolverKey(layouts, state)
+	if e, ok := sv.memo[key]; ok {
+		return e, true
+	}
+
+	// every position with a ship needs a shot, so no way of playing can do better than the average left to hit.
+	var least float64
+	for _, l := range layouts {
+		least += float64(sv.occupied[l].AndNot(state.hits).Count())
+	}
+	least /= float64(len(layouts))
+
+	best := math.Inf(1)
+	for _, p := range sv.likeliest(layouts, state) {
+		e, ok := sv.shoot(layouts, state, p.X, p.Y, best)
+		if !ok {
+			return 0, false
+		}
+		if e < best {
+			best = e
+		}
+		if best <= least {
+			break
+		}
+	}
+
+	if sv.memo != nil {
+		sv.memo[key] = best
+	}
+	return best, true
+}
+
+// likeliest returns the positions worth shooting, most likely to hit first.
+func (sv *solving) likeliest(layouts []int, state solverState) []battleship.Point {
+	var counts [battleship.BoardSize][battleship.BoardSize]int
+	var points []battleship.Point
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			if state.shots.Has(x, y) {
+				continue
+			}
+			for _, l := range layouts {
+				if sv.occupied[l].Has(x, y) {
+					counts[x][y]++
+				}
+			}
+			if counts[x][y] > 0 {
+				points = append(points, battleship.Point{X: x, Y: y})
+			}
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return counts[points[i].X][points[i].Y] > counts[points[j].X][points[j].Y]
+	})
+	return points
+}
+
+// worth returns the positions worth shooting; those some layout has a ship on, that haven't been shot.
+func (sv *solving) worth(layouts []int, state solverState) (worth battleship.Bitboard) {
+	for _, l := range layouts {
+		for i, j := range sv.layouts[l] {
+			worth = worth.Or(sv.masks[i][j])
+		}
+	}
+	return worth.AndNot(state.shots)
+}
+
+// shoot returns the expected number of shots to finish the game, when shooting x,y and then playing perfectly.
+// Once it's clear the result won't be less than bound, it stops, returning a result no less than bound.
+func (sv *solving) shoot(layouts []int, state solverState, x, y int, bound float64) (float64, bool) {
+	sv.steps++
+	if sv.steps > sv.limit {
+		return 0, false
+	}
+
+	// split the layouts by what shooting here would show; a miss, a hit, or sinking a ship.
+	outcomes := make([][]int, len(sv.ships)+2)
+	var shot battleship.Bitboard
+	shot.Set(x, y)
+	hits := state.hits.Or(shot)
+	for _, l := range layouts {
+		outcome := 0
+		for i, j := range sv.layouts[l] {
+			if !sv.masks[i][j].Has(x, y) {
+				continue
+			}
+			outcome = 1
+			if sv.masks[i][j].AndNot(hits).Empty() {
+				outcome = i + 2
+			}
+			break
+		}
+		outcomes[outcome] = append(outcomes[outcome], l)
+	}
+
+	e := 1.0
+	for outcome, ls := range outcomes {
+		if len(ls) == 0 {
+			continue
+		}
+		next := solverState{
+			shots: state.shots.Or(shot),
+			hits:  state.hits,
+			sunk:  state.sunk,
+		}
+		if outcome > 0 {
+			next.hits = hits
+		}
+		if outcome > 1 {
+			next.sunk |= 1 << uint(outcome-2)
+		}
+		ne, ok := sv.expected(ls, next)
+		if !ok {
+			return 0, false
+		}
+		e += ne * float64(len(ls)) / float64(len(layouts))
+		if e >= bound {
+			break
+		}
+	}
+	return e, true
+}
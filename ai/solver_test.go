@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stewi1014/battleship"
+)
+
+func TestSolveEndgame(t *testing.T) {
+	patrolBoat := battleship.Fleet{{Name: "Patrol Boat", Symbol: 'P', Cells: battleship.Line(2)}}
+
+	testCases := []struct {
+		desc  string
+		rules battleship.Rules
+		board func(r battleship.Rules) battleship.Board
+		limit int
+		// wantExpected is the expected number of shots to finish from every position, or 0 if it's not solvable.
+		wantExpected float64
+		wantLayouts  int
+	}{
+		{
+			desc:  "patrol boat on 2x2",
+			rules: battleship.Rules{Fleet: patrolBoat, Size: 2},
+			board: func(r battleship.Rules) battleship.Board {
+				return r.NewBoard()
+			},
+			limit: solverLimit,
+			// half the time the first shot hits, then 1.5 more shots on average finish it, otherwise 2.5 more.
+			wantExpected: 3,
+			wantLayouts:  4,
+		},
+		{
+			desc:  "patrol boat on 2x2 after a miss",
+			rules: battleship.Rules{Fleet: patrolBoat, Size: 2},
+			board: func(r battleship.Rules) battleship.Board {
+				b := r.NewBoard()
+				b.PlayerShot(0, 0, false)
+				return b
+			},
+			limit: solverLimit,
+			// the ship covers the far corner and one of the other two positions; at worst finishing takes 3 shots, at best 2.
+			wantExpected: 2.5,
+			wantLayouts:  2,
+		},
+		{
+			desc: "classic opening is too big",
+			board: func(r battleship.Rules) battleship.Board {
+				return r.NewBoard()
+			},
+			limit: solverLimit,
+		},
+		{
+			desc:  "limited",
+			rules: battleship.Rules{Fleet: patrolBoat, Size: 2},
+			board: func(r battleship.Rules) battleship.Board {
+				return r.NewBoard()
+			},
+			limit: 5,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			b := tC.board(tC.rules)
+			s := NewSampler(tC.rules, rand.New(rand.NewSource(1)))
+			e, ok := s.SolveEndgame(&b, map[byte]battleship.Point{}, tC.limit)
+			if ok != (tC.wantLayouts > 0) {
+				t.Fatalf("got solved %v, want %v", ok, tC.wantLayouts > 0)
+			}
+			if !ok {
+				return
+			}
+			if e.Layouts != tC.wantLayouts {
+				t.Errorf("got %v layouts, want %v", e.Layouts, tC.wantLayouts)
+			}
+
+			for x := 0; x < tC.rules.GetSize(); x++ {
+				for y := 0; y < tC.rules.GetSize(); y++ {
+					if b.PlayerHasShot(x, y) {
+						continue
+					}
+					if tC.wantExpected > 0 && math.Abs(e.Expected[x][y]-tC.wantExpected) > 1e-9 {
+						t.Errorf("got expected %v at %v, want %v", e.Expected[x][y], battleship.FormatPosition(x, y), tC.wantExpected)
+					}
+					if e.Expected[x][y] < e.Expected[e.Best.X][e.Best.Y] {
+						t.Errorf("%v is better than the best shot %v", battleship.FormatPosition(x, y), battleship.FormatPosition(e.Best.X, e.Best.Y))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSolveEndgameMemo(t *testing.T) {
+	// with two ships alike, the same shots can sink a ship at different positions, leaving different layouts.
+	rules := battleship.Rules{
+		Fleet: battleship.Fleet{
+			{Name: "Patrol Boat", Symbol: 'P', Cells: battleship.Line(2)},
+			{Name: "Dinghy", Symbol: 'D', Cells: battleship.Line(2)},
+		},
+		Size: 3,
+	}
+	testCases := []struct {
+		desc   string
+		misses []string
+		hits   []string
+	}{
+		{
+			desc:   "after misses",
+			misses: []string{"a1", "a2", "c1"},
+		},
+		{
+			desc:   "after a hit",
+			misses: []string{"a3", "b3", "c3", "c1"},
+			hits:   []string{"a1"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			b := rules.NewBoard()
+			for _, shots := range []struct {
+				positions []string
+				hit       bool
+			}{{tC.misses, false}, {tC.hits, true}} {
+				for _, position := range shots.positions {
+					x, y, err := battleship.ParsePosition(position)
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.PlayerShot(x, y, shots.hit)
+				}
+			}
+
+			s := NewSampler(rules, rand.New(rand.NewSource(1)))
+			e, ok := s.SolveEndgame(&b, map[byte]battleship.Point{}, solverLimit)
+			if !ok {
+				t.Fatal("not solved")
+			}
+			want, ok := s.solveEndgame(&b, map[byte]battleship.Point{}, math.MaxInt32, nil)
+			if !ok {
+				t.Fatal("not solved without memo")
+			}
+			for x := 0; x < rules.GetSize(); x++ {
+				for y := 0; y < rules.GetSize(); y++ {
+					if math.Abs(e.Expected[x][y]-want.Expected[x][y]) > 1e-9 {
+						t.Errorf("got expected %v at %v, want %v", e.Expected[x][y], battleship.FormatPosition(x, y), want.Expected[x][y])
+					}
+				}
+			}
+		})
+	}
+}
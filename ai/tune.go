@@ -0,0 +1,259 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stewi1014/battleship"
+)
+
+// Limits on AI parameters, keeping tuning to values that make sense.
+const (
+	maxParity    = 5
+	minAIWeight  = 0.01
+	maxAIWeight  = 100
+	tuneMutation = 0.3
+)
+
+// AIParams are the knobs that tune how the classic AI plays.
+type AIParams struct {
+	// Parity is the spacing of the checkerboard the AI hunts on; the positions where x+y is a multiple of it.
+	// Ships at least as long as Parity can't avoid it. 1 hunts everywhere.
+	Parity int `json:"parity"`
+	// OffParity is the weight of hunting positions off the checkerboard, relative to those on it.
+	OffParity float64 `json:"offParity"`
+	// Edge is the weight of hunting positions on the edge of the board, relative to those inside it.
+	Edge float64 `json:"edge"`
+	// Airstrike is how many positions of an airstrike must be unknown for the AI to use one when following up a hit.
+	Airstrike int `json:"airstrike"`
+}
+
+// DefaultAIParams returns the parameters the AI plays with unless told otherwise; hunting everywhere equally.
+func DefaultAIParams() AIParams {
+	return AIParams{
+		Parity:    1,
+		OffParity: 1,
+		Edge:      1,
+		Airstrike: battleship.AirstrikeRadius,
+	}
+}
+
+// LoadAIParams reads parameters saved with Save.
+func LoadAIParams(path string) (AIParams, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return AIParams{}, err
+	}
+	params := DefaultAIParams()
+	if err := json.Unmarshal(data, &params); err != nil {
+		return AIParams{}, err
+	}
+	return params, params.Check()
+}
+
+// Save writes the parameters to a file.
+func (p AIParams) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Check returns an error if the parameters are out of range.
+func (p AIParams) Check() error {
+	switch {
+	case p.Parity < 1 || p.Parity > maxParity:
+		return fmt.Errorf("parity must be between 1 and %v", maxParity)
+	case p.OffParity < minAIWeight || p.OffParity > maxAIWeight:
+		return fmt.Errorf("offParity must be between %v and %v", minAIWeight, maxAIWeight)
+	case p.Edge < minAIWeight || p.Edge > maxAIWeight:
+		return fmt.Errorf("edge must be between %v and %v", minAIWeight, maxAIWeight)
+	case p.Airstrike < 0 || p.Airstrike > 2*battleship.AirstrikeRadius+1:
+		return fmt.Errorf("airstrike must be between 0 and %v", 2*battleship.AirstrikeRadius+1)
+	}
+	return nil
+}
+
+// String returns the parameters as a single line.
+func (p AIParams) String() string {
+	return fmt.Sprintf("parity %v, off parity %.2f, edge %.2f, airstrike %v", p.Parity, p.OffParity, p.Edge, p.Airstrike)
+}
+
+// weight returns how likely the AI should be to hunt at x,y.
+func (p AIParams) weight(rules battleship.Rules, x, y int) float64 {
+	w := 1.0
+	if (x+y)%p.Parity != 0 {
+		w *= p.OffParity
+	}
+	if !rules.InBounds(x-1, y) || !rules.InBounds(x+1, y) || !rules.InBounds(x, y-1) || !rules.InBounds(x, y+1) {
+		w *= p.Edge
+	}
+	return w
+}
+
+// randomAIParams returns parameters picked at random from the whole range, with weights evenly spread by order of magnitude.
+func randomAIParams(rng *rand.Rand) AIParams {
+	weight := func() float64 {
+		return math.Exp(math.Log(minAIWeight) + rng.Float64()*(math.Log(maxAIWeight)-math.Log(minAIWeight)))
+	}
+	return AIParams{
+		Parity:    1 + rng.Intn(maxParity),
+		OffParity: weight(),
+		Edge:      weight(),
+		Airstrike: rng.Intn(2*battleship.AirstrikeRadius + 2),
+	}
+}
+
+// mutate returns a copy of the parameters with random changes.
+func (p AIParams) mutate(rng *rand.Rand) AIParams {
+	step := func(n, lo, hi int) int {
+		if rng.Intn(3) == 0 {
+			n += rng.Intn(3) - 1
+		}
+		if n < lo {
+			return lo
+		}
+		if n > hi {
+			return hi
+		}
+		return n
+	}
+	scale := func(w float64) float64 {
+		return math.Max(minAIWeight, math.Min(maxAIWeight, w*math.Exp(rng.NormFloat64()*tuneMutation)))
+	}
+	return AIParams{
+		Parity:    step(p.Parity, 1, maxParity),
+		OffParity: scale(p.OffParity),
+		Edge:      scale(p.Edge),
+		Airstrike: step(p.Airstrike, 0, 2*battleship.AirstrikeRadius+1),
+	}
+}
+
+// cross returns parameters taking each from p or o at random.
+func (p AIParams) cross(o AIParams, rng *rand.Rand) AIParams {
+	if rng.Intn(2) == 0 {
+		p.Parity = o.Parity
+	}
+	if rng.Intn(2) == 0 {
+		p.OffParity = o.OffParity
+	}
+	if rng.Intn(2) == 0 {
+		p.Edge = o.Edge
+	}
+	if rng.Intn(2) == 0 {
+		p.Airstrike = o.Airstrike
+	}
+	return p
+}
+
+// Tuning searches for the best parameters for the classic AI by self-play, with a genetic algorithm.
+// The first generation is the default parameters and random ones.
+// Each generation, every candidate plays Games games against an AI with the default parameters, half of them going first.
+// The better half of the candidates survive, and are bred to replace the rest.
+type Tuning struct {
+	Rules       battleship.Rules
+	Population  int
+	Generations int
+	Games       int
+}
+
+// Candidate is a set of parameters, and how they did in tuning.
+type Candidate struct {
+	Params AIParams
+	// WinRate is the fraction of games won against the default parameters.
+	WinRate float64
+}
+
+// Run runs the tuning, playing games in parallel across all cores, and returns the best candidate found.
+// progress, if not nil, is called after each generation with the best candidate so far.
+func (t Tuning) Run(progress func(generation int, best Candidate)) (Candidate, error) {
+	if t.Population < 2 || t.Generations < 1 || t.Games < 1 {
+		return Candidate{}, errors.New("tuning needs a population of at least 2, and at least one generation and game")
+	}
+	if err := t.Rules.Check(); err != nil {
+		return Candidate{}, err
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	population := []Candidate{{Params: DefaultAIParams()}}
+	for len(population) < t.Population {
+		population = append(population, Candidate{Params: randomAIParams(rng)})
+	}
+
+	for generation := 1; generation <= t.Generations; generation++ {
+		t.evaluate(population)
+		sort.SliceStable(population, func(i, j int) bool {
+			return population[i].WinRate > population[j].WinRate
+		})
+		if progress != nil {
+			progress(generation, population[0])
+		}
+		if generation == t.Generations {
+			break
+		}
+
+		survivors := (len(population) + 1) / 2
+		for i := survivors; i < len(population); i++ {
+			a, b := population[rng.Intn(survivors)], population[rng.Intn(survivors)]
+			population[i] = Candidate{Params: a.Params.cross(b.Params, rng).mutate(rng)}
+		}
+	}
+	return population[0], nil
+}
+
+// evaluate plays every candidate's games, spread over a worker for each core.
+func (t Tuning) evaluate(population []Candidate) {
+	type game struct {
+		candidate, n int
+	}
+	games := make(chan game)
+	wins := make([]int, len(population))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g := range games {
+				if t.play(population[g.candidate].Params, g.n%2 == 0) {
+					mu.Lock()
+					wins[g.candidate]++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range population {
+		for n := 0; n < t.Games; n++ {
+			games <- game{i, n}
+		}
+	}
+	close(games)
+	wg.Wait()
+
+	for i := range population {
+		population[i].WinRate = float64(wins[i]) / float64(t.Games)
+	}
+}
+
+// play plays a game between an AI with the given parameters and one with the defaults, returning true if the former won.
+func (t Tuning) play(params AIParams, first bool) bool {
+	ai, opponent := NewAI(t.Rules), NewAI(t.Rules)
+	ai.params = params
+	if first {
+		result, _ := battleship.PlayGame(ai, opponent, nil)
+		return result.Winner == 1
+	}
+	result, _ := battleship.PlayGame(opponent, ai, nil)
+	return result.Winner == 2
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stewi1014/battleship"
+)
+
+func TestAIParams(t *testing.T) {
+	params := AIParams{Parity: 2, OffParity: 0.5, Edge: 0.25, Airstrike: battleship.AirstrikeRadius}
+	testCases := []struct {
+		desc   string
+		rules  battleship.Rules
+		x, y   int
+		weight float64
+	}{
+		{
+			desc:   "on parity",
+			x:      3,
+			y:      5,
+			weight: 1,
+		},
+		{
+			desc:   "off parity",
+			x:      3,
+			y:      4,
+			weight: 0.5,
+		},
+		{
+			desc:   "edge",
+			x:      0,
+			y:      4,
+			weight: 0.25,
+		},
+		{
+			desc:   "edge off parity",
+			x:      battleship.BoardSize - 1,
+			y:      battleship.BoardSize - 2,
+			weight: 0.125,
+		},
+		{
+			desc:   "edge of small board",
+			rules:  battleship.Rules{Size: 5},
+			x:      4,
+			y:      1,
+			weight: 0.125,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if w := params.weight(tC.rules, tC.x, tC.y); w != tC.weight {
+				t.Errorf("got weight %v, want %v", w, tC.weight)
+			}
+		})
+	}
+
+	dir, err := ioutil.TempDir("", "battleship")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ai-params.json")
+	if err := params.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadAIParams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != params {
+		t.Errorf("loaded %v, saved %v", loaded, params)
+	}
+
+	if err := (AIParams{Parity: 0, OffParity: 1, Edge: 1}).Check(); err == nil {
+		t.Error("parity 0 passed check")
+	}
+}
+
+func TestTuning(t *testing.T) {
+	tuning := Tuning{
+		Population:  4,
+		Generations: 3,
+		Games:       10,
+	}
+	var generations int
+	best, err := tuning.Run(func(generation int, best Candidate) {
+		generations++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if generations != tuning.Generations {
+		t.Errorf("progress called for %v generations, want %v", generations, tuning.Generations)
+	}
+	if err := best.Params.Check(); err != nil {
+		t.Errorf("best parameters %v are out of range; %v", best.Params, err)
+	}
+	if best.WinRate < 0 || best.WinRate > 1 {
+		t.Errorf("got win rate %v", best.WinRate)
+	}
+}
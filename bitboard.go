@@ -0,0 +1,207 @@
+package battleship
+
+import "math/bits"
+
+// Bitboard is a set of positions on the board, as a bit for each, for fast simulation.
+// Position x,y is bit x*BoardSize+y.
+type Bitboard [2]uint64
+
+// Bitboards of whole areas of the board, used for shifting positions without them wrapping around.
+var (
+	bitsAll, bitsFirstColumn, bitsLastColumn Bitboard
+)
+
+func init() {
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
+			bitsAll.Set(x, y)
+		}
+		bitsFirstColumn.Set(x, 0)
+		bitsLastColumn.Set(x, BoardSize-1)
+	}
+}
+
+// BitboardOf returns the set of the given positions.
+func BitboardOf(points []Point) (m Bitboard) {
+	for _, p := range points {
+		m.Set(p.X, p.Y)
+	}
+	return
+}
+
+// Set adds x,y to the set.
+// x,y should be checked for validity beforehand.
+func (m *Bitboard) Set(x, y int) {
+	i := x*BoardSize + y
+	m[i/64] |= 1 << uint(i%64)
+}
+
+// Has returns true if x,y is in the set.
+// x,y should be checked for validity beforehand.
+func (m Bitboard) Has(x, y int) bool {
+	i := x*BoardSize + y
+	return m[i/64]&(1<<uint(i%64)) > 0
+}
+
+// And returns the positions in both sets.
+func (m Bitboard) And(o Bitboard) Bitboard {
+	return Bitboard{m[0] & o[0], m[1] & o[1]}
+}
+
+// Or returns the positions in either set.
+func (m Bitboard) Or(o Bitboard) Bitboard {
+	return Bitboard{m[0] | o[0], m[1] | o[1]}
+}
+
+// AndNot returns the positions in m that aren't in o.
+func (m Bitboard) AndNot(o Bitboard) Bitboard {
+	return Bitboard{m[0] &^ o[0], m[1] &^ o[1]}
+}
+
+// Not returns the positions on the board that aren't in the set.
+func (m Bitboard) Not() Bitboard {
+	return bitsAll.AndNot(m)
+}
+
+// Empty returns true if there are no positions in the set.
+func (m Bitboard) Empty() bool {
+	return m[0] == 0 && m[1] == 0
+}
+
+// Count returns the number of positions in the set.
+func (m Bitboard) Count() int {
+	return bits.OnesCount64(m[0]) + bits.OnesCount64(m[1])
+}
+
+// Points returns the positions in the set.
+func (m Bitboard) Points() (points []Point) {
+	for w := range m {
+		for word := m[w]; word != 0; word &= word - 1 {
+			i := w*64 + bits.TrailingZeros64(word)
+			points = append(points, Point{i / BoardSize, i % BoardSize})
+		}
+	}
+	return
+}
+
+// Halo returns the positions in the set, and every position next to them, orthogonally or diagonally.
+func (m Bitboard) Halo() Bitboard {
+	h := m.Or(m.AndNot(bitsLastColumn).shiftUp(1)).Or(m.AndNot(bitsFirstColumn).shiftDown(1))
+	return h.Or(h.shiftUp(BoardSize)).Or(h.shiftDown(BoardSize)).And(bitsAll)
+}
+
+// shiftUp moves every position n bits higher.
+func (m Bitboard) shiftUp(n uint) Bitboard {
+	return Bitboard{m[0] << n, m[1]<<n | m[0]>>(64-n)}
+}
+
+// shiftDown moves every position n bits lower.
+func (m Bitboard) shiftDown(n uint) Bitboard {
+	return Bitboard{m[0]>>n | m[1]<<(64-n), m[1] >> n}
+}
+
+// BoardBits is a Board stored as a Bitboard for each ship and flag, for fast simulation.
+// Converting a Board to BoardBits and back gives the same Board.
+type BoardBits struct {
+	// Ships holds the positions of each ship, by index in the fleet, and Occupied the positions of every ship.
+	// Occupied is kept up to date by Place.
+	Ships    [MaxFleetSize]Bitboard
+	Occupied Bitboard
+	// OpponentShots holds the positions the opponent has shot.
+	OpponentShots Bitboard
+	// Shots, Hits, Water and Contacts hold what the player knows of the opponent's board; see PlayerShot, PlayerMarkWater and PlayerSonar.
+	Shots, Hits, Water, Contacts Bitboard
+}
+
+// Bits returns the board as BoardBits.
+func (b *Board) Bits() (bb BoardBits) {
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
+			if ship := b[x][y] & shipMask; ship > 0 {
+				bb.Ships[ship>>5-1].Set(x, y)
+				bb.Occupied.Set(x, y)
+			}
+			for _, flag := range []struct {
+				bit byte
+				set *Bitboard
+			}{
+				{opponentHit, &bb.OpponentShots},
+				{playerShot, &bb.Shots},
+				{playerHit, &bb.Hits},
+				{playerWater, &bb.Water},
+				{playerContact, &bb.Contacts},
+			} {
+				if b[x][y]&flag.bit > 0 {
+					flag.set.Set(x, y)
+				}
+			}
+		}
+	}
+	return
+}
+
+// Board returns the BoardBits as a Board.
+func (bb *BoardBits) Board() (b Board) {
+	for i, ship := range bb.Ships {
+		for _, p := range ship.Points() {
+			b[p.X][p.Y] |= byte(i+1) << 5
+		}
+	}
+	for _, flag := range []struct {
+		bit byte
+		set Bitboard
+	}{
+		{opponentHit, bb.OpponentShots},
+		{playerShot, bb.Shots},
+		{playerHit, bb.Hits},
+		{playerWater, bb.Water},
+		{playerContact, bb.Contacts},
+	} {
+		for _, p := range flag.set.Points() {
+			b[p.X][p.Y] |= flag.bit
+		}
+	}
+	return
+}
+
+// Fits returns true if none of the given positions have a ship on them.
+// To check a ship can be placed, pass its positions, or if ships can't touch, their Halo.
+// Halos are slow to find compared to the check, so are best found once for each placement.
+func (bb *BoardBits) Fits(avoid Bitboard) bool {
+	return avoid.And(bb.Occupied).Empty()
+}
+
+// Place puts ship i, its index in the fleet, on the given positions.
+// The positions should be checked with Fits beforehand.
+func (bb *BoardBits) Place(i int, cells Bitboard) {
+	bb.Ships[i] = bb.Ships[i].Or(cells)
+	bb.Occupied = bb.Occupied.Or(cells)
+}
+
+// OpponentShot executes a shot by the opponent, as Board.OpponentShot.
+func (bb *BoardBits) OpponentShot(x, y int) (hit bool, sunk byte) {
+	bb.OpponentShots.Set(x, y)
+	if !bb.Occupied.Has(x, y) {
+		return false, 0
+	}
+	for i := range bb.Ships {
+		if !bb.Ships[i].Has(x, y) {
+			continue
+		}
+		if bb.Ships[i].AndNot(bb.OpponentShots).Empty() {
+			return true, byte(i+1) << 5
+		}
+		return true, 0
+	}
+	return false, 0
+}
+
+// Sunk returns the number of ships the opponent has sunk.
+func (bb *BoardBits) Sunk() (sunk int) {
+	for i := range bb.Ships {
+		if !bb.Ships[i].Empty() && bb.Ships[i].AndNot(bb.OpponentShots).Empty() {
+			sunk++
+		}
+	}
+	return
+}
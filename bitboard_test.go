@@ -0,0 +1,232 @@
+package battleship
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBitboard(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		points []Point
+		halo   int
+	}{
+		{
+			desc: "empty",
+		},
+		{
+			desc:   "corner",
+			points: []Point{{0, 0}},
+			halo:   4,
+		},
+		{
+			desc:   "far corner",
+			points: []Point{{BoardSize - 1, BoardSize - 1}},
+			halo:   4,
+		},
+		{
+			desc:   "edge",
+			points: []Point{{5, BoardSize - 1}},
+			halo:   6,
+		},
+		{
+			desc:   "middle",
+			points: []Point{{4, 4}},
+			halo:   9,
+		},
+		{
+			desc:   "across words",
+			points: []Point{{6, 3}, {6, 4}, {6, 5}},
+			halo:   15,
+		},
+		{
+			desc:   "line along edge",
+			points: []Point{{0, 3}, {1, 3}, {2, 3}},
+			halo:   12,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			m := BitboardOf(tC.points)
+			if m.Count() != len(tC.points) {
+				t.Errorf("got %v positions, want %v", m.Count(), len(tC.points))
+			}
+			if got := FormatPoints(m.Points()); got != FormatPoints(tC.points) {
+				t.Errorf("got points %v, want %v", got, FormatPoints(tC.points))
+			}
+
+			if not := m.Not(); not.Count() != BoardSize*BoardSize-len(tC.points) || !not.And(m).Empty() {
+				t.Errorf("got %v positions not in the set, want the other %v", not.Count(), BoardSize*BoardSize-len(tC.points))
+			}
+
+			halo := m.Halo()
+			if halo.Count() != tC.halo {
+				t.Errorf("halo has %v positions, want %v", halo.Count(), tC.halo)
+			}
+			for _, p := range halo.Points() {
+				var near bool
+				for _, q := range tC.points {
+					near = near || p.X-q.X <= 1 && q.X-p.X <= 1 && p.Y-q.Y <= 1 && q.Y-p.Y <= 1
+				}
+				if !near {
+					t.Errorf("halo has %v, not next to any position", FormatPosition(p.X, p.Y))
+				}
+			}
+		})
+	}
+}
+
+func TestBoardBits(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, rules := range []Rules{{}, {Fleet: ShapesFleet, NoTouching: true}} {
+		for game := 0; game < 100; game++ {
+			b := RandomBoard(rng, rules)
+			bb := b.Bits()
+			for shot := 0; shot < 60; shot++ {
+				x, y := rng.Intn(BoardSize), rng.Intn(BoardSize)
+				hit, sunk := b.OpponentShot(x, y)
+				bitsHit, bitsSunk := bb.OpponentShot(x, y)
+				if hit != bitsHit || sunk != bitsSunk {
+					t.Fatalf("shot at %v was %v %v on the board, but %v %v on the bits", FormatPosition(x, y), hit, sunk, bitsHit, bitsSunk)
+				}
+				b.PlayerShot(x, y, hit)
+				if shot%10 == 0 {
+					b.PlayerSonar(x, y, hit)
+				}
+			}
+
+			bb = b.Bits()
+			if bb.Board() != b {
+				t.Fatal("board changed converting to bits and back")
+			}
+			var sunk int
+			for _, ship := range rules.GetFleet().Ships() {
+				for x := 0; x < BoardSize; x++ {
+					for y := 0; y < BoardSize; y++ {
+						if b[x][y]&shipMask == ship && b.IsSunk(x, y) {
+							sunk++
+							x, y = BoardSize, BoardSize
+						}
+					}
+				}
+			}
+			if bb.Sunk() != sunk {
+				t.Fatalf("bits have %v ships sunk, the board %v", bb.Sunk(), sunk)
+			}
+		}
+	}
+}
+
+// The benchmarks compare the same work done on a Board and on BoardBits.
+// Results are counted into benchSink so the work can't be optimised away.
+var benchSink int
+
+func BenchmarkGame(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	board := RandomBoard(rng, Rules{})
+	order := rng.Perm(BoardSize * BoardSize)
+	ships := len(ClassicFleet)
+
+	b.Run("board", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			game := board
+			var sunk int
+			for _, n := range order {
+				if _, s := game.OpponentShot(n/BoardSize, n%BoardSize); s != 0 {
+					if sunk++; sunk == ships {
+						break
+					}
+				}
+			}
+		}
+	})
+	b.Run("bits", func(b *testing.B) {
+		bits := board.Bits()
+		for i := 0; i < b.N; i++ {
+			game := bits
+			var sunk int
+			for _, n := range order {
+				if _, s := game.OpponentShot(n/BoardSize, n%BoardSize); s != 0 {
+					if sunk++; sunk == ships {
+						break
+					}
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkFits(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	rules := Rules{NoTouching: true}
+	board := RandomBoard(rng, rules)
+	placements := rules.Placements(ShipCarrier)
+
+	b.Run("board", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, cells := range placements {
+				if boardFits(&board, cells) {
+					benchSink++
+				}
+			}
+		}
+	})
+	b.Run("bits", func(b *testing.B) {
+		bits := board.Bits()
+		masks := make([]Bitboard, len(placements))
+		for i, cells := range placements {
+			masks[i] = BitboardOf(cells).Halo()
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, mask := range masks {
+				if bits.Fits(mask) {
+					benchSink++
+				}
+			}
+		}
+	})
+}
+
+// boardFits is BoardBits.Fits with noTouching, on a Board.
+func boardFits(b *Board, cells []Point) bool {
+	for _, cell := range cells {
+		for ix := cell.X - 1; ix <= cell.X+1; ix++ {
+			for iy := cell.Y - 1; iy <= cell.Y+1; iy++ {
+				if IsValid(ix, iy) && b[ix][iy]&shipMask > 0 {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func BenchmarkCountHits(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	board := RandomBoard(rng, Rules{})
+	for n := 0; n < 50; n++ {
+		x, y := rng.Intn(BoardSize), rng.Intn(BoardSize)
+		board.PlayerShot(x, y, rng.Intn(3) == 0)
+	}
+
+	b.Run("board", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var hits int
+			for x := 0; x < BoardSize; x++ {
+				for y := 0; y < BoardSize; y++ {
+					if board.PlayerHasHit(x, y) {
+						hits++
+					}
+				}
+			}
+			benchSink += hits
+		}
+	})
+	b.Run("bits", func(b *testing.B) {
+		bits := board.Bits()
+		for i := 0; i < b.N; i++ {
+			benchSink += bits.Hits.Count()
+		}
+	})
+}
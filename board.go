@@ -1,4 +1,7 @@
-package main
+// Package battleship is the game of battleship; boards, fleets and the rules they're played by,
+// and the Player and Link interfaces players take their turns through.
+// AIs, external bots and a terminal UI that implement Player are in the packages below it.
+package battleship
 
 import (
 	"errors"
@@ -9,85 +12,123 @@ import (
 )
 
 // Constants for the byte describing a position on the board.
-// The first 3 bits are an integer describing the ship type,
+// The first 3 bits are an integer describing the ship type, its index in the Fleet plus one,
 // with the remaining bits used as flags for shots taken by the player and opponent.
-// Currently only 3 flags are used; the middle 2 bytes are unused.
 const (
-	playerShot  = 1 << iota // 00000001
-	playerHit               // 00000010
-	opponentHit             // 00000100
-
-	shipMask       = (1<<8 - 1) &^ (1<<5 - 1) // 11100000
-	shipCarrier    = 1 << 5
-	shipBattleship = 2 << 5
-	shipDestroyer  = 3 << 5
-	shipSubmarine  = 4 << 5
-	shipPatrolBoat = 5 << 5
+	playerShot    = 1 << iota // 00000001
+	playerHit                 // 00000010
+	opponentHit               // 00000100
+	playerWater               // 00001000; the player knows there is no ship here without having shot it.
+	playerContact             // 00010000; a sonar ping found a ship somewhere in an area including this position.
+
+	shipMask = (1<<8 - 1) &^ (1<<5 - 1) // 11100000
+
+	// Ship types in the classic fleet.
+	ShipCarrier    = 1 << 5
+	ShipBattleship = 2 << 5
+	ShipDestroyer  = 3 << 5
+	ShipSubmarine  = 4 << 5
+	ShipPatrolBoat = 5 << 5
 )
 
-var shipNames = map[byte]string{
-	shipCarrier:    "Carrier",
-	shipBattleship: "Battleship",
-	shipDestroyer:  "Destroyer",
-	shipSubmarine:  "Submarine",
-	shipPatrolBoat: "Patrol Boat",
-}
-
 // Constants for defining direction,
 // i.e. placement of ships.
 const (
-	up = 1 + iota // 1 offset, so the nil-case for direction is invalid.
-	down
-	left
-	right
+	Up = 1 + iota // 1 offset, so the nil-case for direction is invalid.
+	Down
+	Left
+	Right
+
+	// diagonal directions, only straight ships can be placed diagonally.
+	UpLeft
+	UpRight
+	DownLeft
+	DownRight
+
+	// Mirrored can be combined with a direction to reflect an odd shaped ship before it is turned.
+	Mirrored = 1 << 4
 )
 
+// DirectionNames maps the directions, as they are typed, to their constants.
+var DirectionNames = map[string]int{
+	"up":        Up,
+	"down":      Down,
+	"left":      Left,
+	"right":     Right,
+	"upleft":    UpLeft,
+	"upright":   UpRight,
+	"downleft":  DownLeft,
+	"downright": DownRight,
+}
+
 // Dimensions of the board.
-const boardSize = 10
+const BoardSize = 10
 
 // IsValid returns true if the given coordinates are a location on the board.
 func IsValid(x, y int) bool {
-	if x < 0 || x >= boardSize {
+	if x < 0 || x >= BoardSize {
 		return false
 	}
-	if y < 0 || y >= boardSize {
+	if y < 0 || y >= BoardSize {
 		return false
 	}
 	return true
 }
 
-// RandomBoard creates a board with randomly placed ships
-func RandomBoard(rng *rand.Rand) Board {
-	var b Board
-	var ships = []byte{
-		shipCarrier,
-		shipBattleship,
-		shipDestroyer,
-		shipSubmarine,
-		shipPatrolBoat,
-	}
+// randomBoardTries is how many random placements RandomBoard tries for a ship before starting over,
+// as the ships already placed can leave no room for it.
+const randomBoardTries = 1000
+
+// randomBoardRestarts is how many times RandomBoard starts over before checking the fleet fits at all.
+const randomBoardRestarts = 100
 
-	for i := 0; i < len(ships); {
-		x := rng.Intn(boardSize)
-		y := rng.Intn(boardSize)
-		direction := rng.Intn(4) + 1
-		if err := b.PlaceShip(x, y, direction, ships[i]); err == nil {
-			// sucessful placement, move on to next ship
-			i++
+// RandomBoard creates a board with randomly placed ships, placed according to the given rules.
+// Ships are placed one at a time, so not every layout is equally likely; see UniformGenerator.
+// It panics if the rules can't be played by, as the fleet would never fit; see Rules.Check.
+func RandomBoard(rng *rand.Rand, rules Rules) Board {
+	ships := rules.GetFleet().Ships()
+
+startOver:
+	for restarts := 0; ; restarts++ {
+		if restarts == randomBoardRestarts {
+			mustCheck(rules)
 		}
-	}
 
-	return b
+		b := rules.NewBoard()
+		for i := 0; i < len(ships); {
+			for tries := 0; ; tries++ {
+				if tries == randomBoardTries {
+					continue startOver
+				}
+
+				x := rng.Intn(rules.GetSize())
+				y := rng.Intn(rules.GetSize())
+				direction := rng.Intn(4) + 1
+				if rules.Diagonal {
+					direction = rng.Intn(8) + 1
+				}
+				if rng.Intn(2) == 0 {
+					direction |= Mirrored
+				}
+				if err := rules.PlaceShip(&b, x, y, direction, ships[i]); err == nil {
+					// sucessful placement, move on to next ship
+					i++
+					break
+				}
+			}
+		}
+		return b
+	}
 }
 
 // Board holds information about the players board, with a byte describing the state of the given position.
 // This does not hold the entire game's state, but rather just one player's boards.
-type Board [boardSize][boardSize]byte
+type Board [BoardSize][BoardSize]byte
 
 // Clear clears the board
 func (b *Board) Clear() {
-	for x := 0; x < boardSize; x++ {
-		for y := 0; y < boardSize; y++ {
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
 			b[x][y] = 0
 		}
 	}
@@ -130,9 +171,131 @@ func (b *Board) PlayerHasHit(x, y int) bool {
 	return b[x][y]&playerHit > 0
 }
 
-// String formats the board as a string.
+// PlayerMarkWater records that the player knows there is no enemy ship at the given position,
+// without having shot it.
+// x,y should be checked for validity beforehand.
+func (b *Board) PlayerMarkWater(x, y int) {
+	b[x][y] |= playerWater
+}
+
+// PlayerKnowsWater returns true if the position has been marked as water with PlayerMarkWater.
+// x,y should be checked for validity beforehand.
+func (b *Board) PlayerKnowsWater(x, y int) bool {
+	return b[x][y]&playerWater > 0
+}
+
+// PlayerSonar records the result of a sonar ping centred on x,y.
+// If contact is false, every position in the area is marked as water,
+// otherwise positions in the area the player knows nothing about are marked as contacts.
+// x,y should be checked for validity beforehand.
+func (b *Board) PlayerSonar(x, y int, contact bool) {
+	for ix := x - SonarRadius; ix <= x+SonarRadius; ix++ {
+		for iy := y - SonarRadius; iy <= y+SonarRadius; iy++ {
+			if !IsValid(ix, iy) || b[ix][iy]&(playerShot|playerWater) > 0 {
+				continue
+			}
+			if contact {
+				b[ix][iy] |= playerContact
+			} else {
+				b[ix][iy] |= playerWater
+			}
+		}
+	}
+}
+
+// PlayerHasContact returns true if a sonar ping found a ship in an area including the given position.
+// x,y should be checked for validity beforehand.
+func (b *Board) PlayerHasContact(x, y int) bool {
+	return b[x][y]&playerContact > 0
+}
+
+// OpponentSonar executes a sonar ping by the opponent, returning true if any ship occupies the area centred on x,y.
+// x,y should be checked for validity beforehand.
+func (b *Board) OpponentSonar(x, y int) bool {
+	for ix := x - SonarRadius; ix <= x+SonarRadius; ix++ {
+		for iy := y - SonarRadius; iy <= y+SonarRadius; iy++ {
+			if IsValid(ix, iy) && b[ix][iy]&shipMask > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OpponentAirstrike executes an airstrike by the opponent on the row segment centred on x,y,
+// returning the result of the shot at every position in the segment the opponent hadn't already shot.
+// x,y should be checked for validity beforehand.
+func (b *Board) OpponentAirstrike(x, y int) []Shot {
+	var shots []Shot
+	for ix := x - AirstrikeRadius; ix <= x+AirstrikeRadius; ix++ {
+		if !IsValid(ix, y) || b[ix][y]&opponentHit > 0 {
+			continue
+		}
+		hit, sunk := b.OpponentShot(ix, y)
+		shots = append(shots, Shot{X: ix, Y: y, Hit: hit, Sunk: sunk})
+	}
+	return shots
+}
+
+// OpponentTorpedo executes a torpedo fired by the opponent along row y, from the left edge, or the right if fromRight is true.
+// The torpedo travels until it reaches a ship position the opponent hasn't hit yet.
+// If ok is false, the torpedo ran off the board without hitting anything.
+// y should be checked for validity beforehand.
+func (b *Board) OpponentTorpedo(y int, fromRight bool) (shot Shot, ok bool) {
+	x, mx := 0, 1
+	if fromRight {
+		x, mx = BoardSize-1, -1
+	}
+
+	for ; IsValid(x, y); x += mx {
+		if b[x][y]&shipMask > 0 && b[x][y]&opponentHit == 0 {
+			hit, sunk := b.OpponentShot(x, y)
+			return Shot{X: x, Y: y, Hit: hit, Sunk: sunk}, true
+		}
+	}
+	return Shot{}, false
+}
+
+// ShipAt returns the type of the ship at the given position, or 0 if there is no ship there.
+// x,y should be checked for validity beforehand.
+func (b *Board) ShipAt(x, y int) byte {
+	return b[x][y] & shipMask
+}
+
+// HasShipNear returns true if there is a ship at, or next to (orthogonally or diagonally), the given position.
+// x,y should be checked for validity beforehand.
+func (b *Board) HasShipNear(x, y int) bool {
+	for ix := x - 1; ix <= x+1; ix++ {
+		for iy := y - 1; iy <= y+1; iy++ {
+			if IsValid(ix, iy) && b[ix][iy]&shipMask > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String formats the board as a string, drawing ships from the classic fleet.
 // It implements fmt.Stringer, so directly passing the board to a print call is a valid way of printing the board.
-func (b Board) String() (str string) {
+func (b Board) String() string {
+	return b.Format(ClassicFleet)
+}
+
+// Format formats the board as a string, drawing ships with the symbols from the given fleet.
+func (b Board) Format(fleet Fleet) string {
+	return b.FormatIncoming(fleet, nil)
+}
+
+// FormatIncoming formats the board as Format does, highlighting the positions of the given incoming fire on the bottom board;
+// hits are drawn with ! rather than X, and misses, which aren't otherwise drawn, with *. Sonar pings aren't drawn.
+func (b Board) FormatIncoming(fleet Fleet, incoming []IncomingFire) (str string) {
+	var latest [BoardSize][BoardSize]bool
+	for _, fire := range incoming {
+		if fire.Weapon != WeaponSonar {
+			latest[fire.X][fire.Y] = true
+		}
+	}
+
 	// this function involves a lot of unoptimised string concatentation,
 	// and when used to play the game, isn't the most astetically appealing.
 	// Improvements can be made here, or ever a graphical soulution substituted here.
@@ -140,14 +303,18 @@ func (b Board) String() (str string) {
 	// Top board; show shots by this player
 	str += "  1 2 3 4 5 6 7 8 9 10\n"
 	// iterate over y in reverse becuase coordinates start at the bottom left, but we print from top left.
-	for y := boardSize - 1; y >= 0; y-- {
-		str += string('A'+y) + " "
-		for x := 0; x < boardSize; x++ {
+	for y := BoardSize - 1; y >= 0; y-- {
+		str += string(rune('A'+y)) + " "
+		for x := 0; x < BoardSize; x++ {
 			switch {
 			case b[x][y]&playerHit > 0:
 				str += "X "
 			case b[x][y]&playerShot > 0:
 				str += "O "
+			case b[x][y]&playerWater > 0:
+				str += "~ "
+			case b[x][y]&playerContact > 0:
+				str += "? "
 			default:
 				str += "  "
 			}
@@ -158,25 +325,30 @@ func (b Board) String() (str string) {
 
 	// Bottom board; show player ships and opponent shots
 	str += "  1 2 3 4 5 6 7 8 9 10\n"
-	for y := boardSize - 1; y >= 0; y-- {
-		str += string('A'+y) + " "
-		for x := 0; x < boardSize; x++ {
+	for y := BoardSize - 1; y >= 0; y-- {
+		str += string(rune('A'+y)) + " "
+		for x := 0; x < BoardSize; x++ {
+			ship := b[x][y] & shipMask
+			class, ok := fleet.Class(ship)
 			switch {
-			case b[x][y]&shipMask > 0 && b[x][y]&opponentHit > 0:
+			case latest[x][y] && ship > 0:
+				str += "!"
+			case latest[x][y]:
+				str += "*"
+			case ship > 0 && b[x][y]&opponentHit > 0:
 				// is a ship, and has been hit
-				str += "X "
-			case b[x][y]&shipMask == shipCarrier:
-				str += "C "
-			case b[x][y]&shipMask == shipBattleship:
-				str += "B "
-			case b[x][y]&shipMask == shipDestroyer:
-				str += "D "
-			case b[x][y]&shipMask == shipSubmarine:
-				str += "S "
-			case b[x][y]&shipMask == shipPatrolBoat:
-				str += "P "
+				str += "X"
+			case ok:
+				str += string(rune(class.Symbol))
 			default:
-				str += "  "
+				str += " "
+			}
+
+			// join positions of the same ship, so their outlines can be seen.
+			if ship > 0 && x+1 < BoardSize && b[x+1][y]&shipMask == ship {
+				str += "-"
+			} else {
+				str += " "
 			}
 		}
 		str += "\n"
@@ -185,58 +357,56 @@ func (b Board) String() (str string) {
 	return
 }
 
-// PlaceShip places a ship on the board. If err is non-nil, the board will have not been modified.
-func (b *Board) PlaceShip(x, y, direction int, shipType byte) error {
-	// mx and my make a vector, added to x,y length times to traverse positions on the ship.
-	var length, mx, my int
-
-	switch shipType {
-	case shipCarrier:
-		length = 5
-	case shipBattleship:
-		length = 4
-	case shipDestroyer:
-		length = 3
-	case shipSubmarine:
-		length = 3
-	case shipPatrolBoat:
-		length = 2
-	default:
-		return errors.New("invalid ship type")
+// FormatHeatmap formats the chance of a ship being at each position, as a percentage, over the positions the board's player hasn't shot yet.
+// Shots are drawn as on the top board of Format.
+func (b Board) FormatHeatmap(heat *[BoardSize][BoardSize]float64) string {
+	var sb strings.Builder
+	sb.WriteString("   1  2  3  4  5  6  7  8  9 10\n")
+	for y := BoardSize - 1; y >= 0; y-- {
+		sb.WriteString(string(rune('A' + y)))
+		for x := 0; x < BoardSize; x++ {
+			switch {
+			case b[x][y]&playerHit > 0:
+				sb.WriteString("  X")
+			case b[x][y]&playerShot > 0:
+				sb.WriteString("  O")
+			case b[x][y]&playerWater > 0:
+				sb.WriteString("  ~")
+			default:
+				percent := int(heat[x][y]*100 + 0.5)
+				if percent > 99 {
+					// keep to two digits; it's as good as certain.
+					percent = 99
+				}
+				fmt.Fprintf(&sb, "%3d", percent)
+			}
+		}
+		sb.WriteString("\n")
 	}
+	return sb.String()
+}
 
-	switch direction {
-	case left:
-		mx = -1
-	case right:
-		mx = 1
-	case down:
-		my = -1
-	case up:
-		my = 1
-	default:
-		return errors.New("invalid direction")
-	}
+// PlaceShip places a ship from the classic fleet on the board. If err is non-nil, the board will have not been modified.
+func (b *Board) PlaceShip(x, y, direction int, shipType byte) error {
+	return Rules{}.PlaceShip(b, x, y, direction, shipType)
+}
 
+// PlaceCells places a ship covering the given positions on the board. If err is non-nil, the board will have not been modified.
+func (b *Board) PlaceCells(cells []Point, shipType byte) error {
 	// check we're not overwriting another ship, and that all positions are valid.
 	// we must be certain everything is fine before we modify the board, else we leave half-written ships on it.
-	ix, iy := x, y // can't modify x and y yet, we need it later.
-	for i := 0; i < length; i++ {
-		if !IsValid(ix, iy) {
+	for _, cell := range cells {
+		if !IsValid(cell.X, cell.Y) {
 			return errors.New("ship is off the board")
 		}
-		if b[ix][iy]&shipMask > 0 {
-			return fmt.Errorf("there is already a ship at %v", FormatPosition(ix, iy))
+		if b[cell.X][cell.Y]&shipMask > 0 {
+			return fmt.Errorf("there is already a ship at %v", FormatPosition(cell.X, cell.Y))
 		}
-		ix += mx
-		iy += my
 	}
 
 	// Everything is good to go, place the ship on the board.
-	for i := 0; i < length; i++ {
-		b[x][y] = shipType
-		x += mx
-		y += my
+	for _, cell := range cells {
+		b[cell.X][cell.Y] = shipType
 	}
 
 	return nil
@@ -251,66 +421,14 @@ func (b *Board) IsSunk(x, y int) bool {
 		return false
 	}
 
-	// Figure out if the ship is placed horizontally or verically, and traverse its positions.
-	var vertical bool
-	// Check if the position above and below are of the same ship type
-	// if they are, we know the ship is placed vertically.
-	if y+1 < boardSize && b[x][y+1]&shipMask == shipType ||
-		y-1 >= 0 && b[x][y-1]&shipMask == shipType {
-		vertical = true
-	}
-
-	// check positions in positive direction, including x,y
-	ix, iy := x, y
-	for i := 0; ; i++ {
-		if !IsValid(ix, iy) {
-			// we're off the board
-			break
-		}
-
-		// travel in the positive direction
-		if b[ix][iy]&shipMask != shipType {
-			// we're no longer on the ship.
-			break
-		}
-
-		if b[ix][iy]&opponentHit == 0 {
-			// ship is not hit at this location.
-			return false
-		}
-
-		if vertical {
-			iy++
-		} else {
-			ix++
-		}
-	}
-
-	// traverse in negative direction, skipping x,y
-	ix, iy = x, y
-	for i := 0; ; i++ {
-		// We've already checked the starting position in the last loop,
-		// so do the increment here and check from the next position onwards.
-		if vertical {
-			iy--
-		} else {
-			ix--
-		}
-
-		if !IsValid(ix, iy) {
-			// we're off the board
-			break
-		}
-
-		// travel in the negative direction
-		if b[ix][iy]&shipMask != shipType {
-			// we're no longer on the ship.
-			break
-		}
-
-		if b[ix][iy]&opponentHit == 0 {
-			// ship is not hit at this location.
-			return false
+	// Ships can be any shape, so rather than following the ship from x,y,
+	// look for any position on the same ship that hasn't been hit.
+	for ix := 0; ix < BoardSize; ix++ {
+		for iy := 0; iy < BoardSize; iy++ {
+			if b[ix][iy]&shipMask == shipType && b[ix][iy]&opponentHit == 0 {
+				// ship is not hit at this location.
+				return false
+			}
 		}
 	}
 
@@ -343,3 +461,12 @@ func ParsePosition(location string) (x int, y int, err error) {
 func FormatPosition(x, y int) string {
 	return string('a'+rune(y)) + strconv.Itoa(x+1)
 }
+
+// FormatPoints formats a list of points as positions, separated by spaces.
+func FormatPoints(points []Point) string {
+	positions := make([]string, len(points))
+	for i, p := range points {
+		positions[i] = FormatPosition(p.X, p.Y)
+	}
+	return strings.Join(positions, " ")
+}
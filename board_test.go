@@ -1,6 +1,8 @@
-package main
+package battleship
 
 import (
+	"math/rand"
+	"strings"
 	"testing"
 )
 
@@ -22,14 +24,14 @@ func TestPlaceShip(t *testing.T) {
 			args: args{
 				x:         4,
 				y:         2,
-				direction: up,
-				shipType:  shipDestroyer,
+				direction: Up,
+				shipType:  ShipDestroyer,
 			},
 			wantBoard: func() Board {
 				var b Board
-				b[4][2] = shipDestroyer
-				b[4][3] = shipDestroyer
-				b[4][4] = shipDestroyer
+				b[4][2] = ShipDestroyer
+				b[4][3] = ShipDestroyer
+				b[4][4] = ShipDestroyer
 				return b
 			}(),
 		},
@@ -38,16 +40,16 @@ func TestPlaceShip(t *testing.T) {
 			args: args{
 				x:         8,
 				y:         5,
-				direction: down,
-				shipType:  shipCarrier,
+				direction: Down,
+				shipType:  ShipCarrier,
 			},
 			wantBoard: func() Board {
 				var b Board
-				b[8][5] = shipCarrier
-				b[8][4] = shipCarrier
-				b[8][3] = shipCarrier
-				b[8][2] = shipCarrier
-				b[8][1] = shipCarrier
+				b[8][5] = ShipCarrier
+				b[8][4] = ShipCarrier
+				b[8][3] = ShipCarrier
+				b[8][2] = ShipCarrier
+				b[8][1] = ShipCarrier
 				return b
 			}(),
 		},
@@ -56,15 +58,15 @@ func TestPlaceShip(t *testing.T) {
 			args: args{
 				x:         5,
 				y:         5,
-				direction: left,
-				shipType:  shipBattleship,
+				direction: Left,
+				shipType:  ShipBattleship,
 			},
 			wantBoard: func() Board {
 				var b Board
-				b[5][5] = shipBattleship
-				b[4][5] = shipBattleship
-				b[3][5] = shipBattleship
-				b[2][5] = shipBattleship
+				b[5][5] = ShipBattleship
+				b[4][5] = ShipBattleship
+				b[3][5] = ShipBattleship
+				b[2][5] = ShipBattleship
 				return b
 			}(),
 		},
@@ -73,29 +75,29 @@ func TestPlaceShip(t *testing.T) {
 			args: args{
 				x:         0,
 				y:         0,
-				direction: right,
-				shipType:  shipSubmarine,
+				direction: Right,
+				shipType:  ShipSubmarine,
 			},
 			wantBoard: func() Board {
 				var b Board
-				b[0][0] = shipSubmarine
-				b[1][0] = shipSubmarine
-				b[2][0] = shipSubmarine
+				b[0][0] = ShipSubmarine
+				b[1][0] = ShipSubmarine
+				b[2][0] = ShipSubmarine
 				return b
 			}(),
 		},
 		{
 			desc: "PatrolBoat placed downwards at edge of board",
 			args: args{
-				x:         boardSize - 1,
-				y:         boardSize - 1,
-				direction: down,
-				shipType:  shipPatrolBoat,
+				x:         BoardSize - 1,
+				y:         BoardSize - 1,
+				direction: Down,
+				shipType:  ShipPatrolBoat,
 			},
 			wantBoard: func() Board {
 				var b Board
-				b[boardSize-1][boardSize-1] = shipPatrolBoat
-				b[boardSize-1][boardSize-2] = shipPatrolBoat
+				b[BoardSize-1][BoardSize-1] = ShipPatrolBoat
+				b[BoardSize-1][BoardSize-2] = ShipPatrolBoat
 				return b
 			}(),
 		},
@@ -103,9 +105,9 @@ func TestPlaceShip(t *testing.T) {
 			desc: "top edge error",
 			args: args{
 				x:         5,
-				y:         boardSize - 1,
-				direction: up,
-				shipType:  shipPatrolBoat,
+				y:         BoardSize - 1,
+				direction: Up,
+				shipType:  ShipPatrolBoat,
 			},
 			wantError: true,
 		},
@@ -114,8 +116,8 @@ func TestPlaceShip(t *testing.T) {
 			args: args{
 				x:         5,
 				y:         0,
-				direction: down,
-				shipType:  shipPatrolBoat,
+				direction: Down,
+				shipType:  ShipPatrolBoat,
 			},
 			wantError: true,
 		},
@@ -124,18 +126,18 @@ func TestPlaceShip(t *testing.T) {
 			args: args{
 				x:         0,
 				y:         5,
-				direction: left,
-				shipType:  shipPatrolBoat,
+				direction: Left,
+				shipType:  ShipPatrolBoat,
 			},
 			wantError: true,
 		},
 		{
 			desc: "right edge error",
 			args: args{
-				x:         boardSize - 1,
+				x:         BoardSize - 1,
 				y:         5,
-				direction: right,
-				shipType:  shipPatrolBoat,
+				direction: Right,
+				shipType:  ShipPatrolBoat,
 			},
 			wantError: true,
 		},
@@ -144,16 +146,16 @@ func TestPlaceShip(t *testing.T) {
 			args: args{
 				x:         7,
 				y:         3,
-				direction: right,
-				shipType:  shipPatrolBoat,
+				direction: Right,
+				shipType:  ShipPatrolBoat,
 			},
 			startBoard: func() Board {
 				var b Board
-				b[8][5] = shipCarrier
-				b[8][4] = shipCarrier
-				b[8][3] = shipCarrier
-				b[8][2] = shipCarrier
-				b[8][1] = shipCarrier
+				b[8][5] = ShipCarrier
+				b[8][4] = ShipCarrier
+				b[8][3] = ShipCarrier
+				b[8][2] = ShipCarrier
+				b[8][1] = ShipCarrier
 				return b
 			}(),
 			wantError: true,
@@ -248,3 +250,313 @@ func TestParsePosition(t *testing.T) {
 		})
 	}
 }
+
+func TestRulesPlaceShip(t *testing.T) {
+	// a destroyer placed vertically from e5 to g5
+	startBoard := func() Board {
+		var b Board
+		b[4][4] = ShipDestroyer
+		b[4][5] = ShipDestroyer
+		b[4][6] = ShipDestroyer
+		return b
+	}()
+
+	type args struct {
+		x, y      int
+		direction int
+	}
+	testCases := []struct {
+		desc      string
+		rules     Rules
+		args      args
+		wantError bool
+	}{
+		{
+			desc:  "touching side allowed",
+			rules: Rules{},
+			args:  args{x: 5, y: 4, direction: Up},
+		},
+		{
+			desc:      "touching side",
+			rules:     Rules{NoTouching: true},
+			args:      args{x: 5, y: 4, direction: Up},
+			wantError: true,
+		},
+		{
+			desc:      "touching end",
+			rules:     Rules{NoTouching: true},
+			args:      args{x: 4, y: 7, direction: Up},
+			wantError: true,
+		},
+		{
+			desc:      "touching diagonally",
+			rules:     Rules{NoTouching: true},
+			args:      args{x: 5, y: 7, direction: Right},
+			wantError: true,
+		},
+		{
+			desc:  "one space gap",
+			rules: Rules{NoTouching: true},
+			args:  args{x: 6, y: 4, direction: Up},
+		},
+		{
+			desc:      "diagonal not allowed",
+			rules:     Rules{},
+			args:      args{x: 0, y: 0, direction: UpRight},
+			wantError: true,
+		},
+		{
+			desc:  "diagonal touching allowed",
+			rules: Rules{Diagonal: true},
+			args:  args{x: 5, y: 7, direction: DownRight},
+		},
+		{
+			desc:      "diagonal touching",
+			rules:     Rules{Diagonal: true, NoTouching: true},
+			args:      args{x: 5, y: 7, direction: DownRight},
+			wantError: true,
+		},
+		{
+			desc:      "diagonal off the board",
+			rules:     Rules{Diagonal: true},
+			args:      args{x: 9, y: 0, direction: DownLeft},
+			wantError: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			board := startBoard
+
+			err := tC.rules.PlaceShip(&board, tC.args.x, tC.args.y, tC.args.direction, ShipPatrolBoat)
+			if tC.wantError {
+				if err == nil {
+					t.Fatalf("wanted error, got \n%v", board)
+				}
+				if board != startBoard {
+					t.Fatalf("PlaceShip returned error (good), but it modified the board!\n%v", board)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRandomBoardNoTouching(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		b := RandomBoard(rng, Rules{NoTouching: true})
+		for x := 0; x < BoardSize; x++ {
+			for y := 0; y < BoardSize; y++ {
+				ship := b[x][y] & shipMask
+				if ship == 0 {
+					continue
+				}
+				// only the same ship may be next to this position.
+				for ix := x - 1; ix <= x+1; ix++ {
+					for iy := y - 1; iy <= y+1; iy++ {
+						if IsValid(ix, iy) && b[ix][iy]&shipMask != 0 && b[ix][iy]&shipMask != ship {
+							t.Fatalf("ships touching at %v\n%v", FormatPosition(x, y), b)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRandomBoardStartsOver(t *testing.T) {
+	// four patrol boats only fit on a 3x3 board if they're placed just so; placing them one at a time often leaves no room.
+	patrolBoat := ShipClass{Name: "Patrol Boat", Symbol: 'P', Cells: Line(2)}
+	rules := Rules{Fleet: Fleet{patrolBoat, patrolBoat, patrolBoat, patrolBoat}, Size: 3}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		b := RandomBoard(rng, rules)
+		var cells int
+		for x := 0; x < BoardSize; x++ {
+			for y := 0; y < BoardSize; y++ {
+				if b.ShipAt(x, y) != 0 {
+					cells++
+				}
+			}
+		}
+		if cells != 8 {
+			t.Fatalf("got %v positions with ships, want 8\n%v", cells, b)
+		}
+	}
+}
+
+func TestRandomBoardDoesntFit(t *testing.T) {
+	// each patrol boat fits on a 2x2 board, but not all three.
+	patrolBoat := ShipClass{Name: "Patrol Boat", Symbol: 'P', Cells: Line(2)}
+	rules := Rules{Fleet: Fleet{patrolBoat, patrolBoat, patrolBoat}, Size: 2}
+	defer func() {
+		if recover() == nil {
+			t.Error("wanted a panic for a fleet that doesn't fit")
+		}
+	}()
+	RandomBoard(rand.New(rand.NewSource(1)), rules)
+}
+
+func TestRulesCheck(t *testing.T) {
+	patrolBoat := ShipClass{Name: "Patrol Boat", Symbol: 'P', Cells: Line(2)}
+	testCases := []struct {
+		desc  string
+		rules Rules
+		fits  bool
+	}{
+		{
+			desc: "classic",
+			fits: true,
+		},
+		{
+			// the shapes only fit a 7x7 board without touching a few ways; picking layouts at random almost never finds one.
+			desc:  "tight shapes",
+			rules: Rules{Size: 7, Fleet: ShapesFleet, NoTouching: true},
+			fits:  true,
+		},
+		{
+			desc:  "shapes too tight",
+			rules: Rules{Size: 6, Fleet: ShapesFleet, NoTouching: true},
+		},
+		{
+			desc:  "patrol boats too tight",
+			rules: Rules{Size: 2, Fleet: Fleet{patrolBoat, patrolBoat, patrolBoat}},
+		},
+		{
+			desc:  "too big",
+			rules: Rules{Size: BoardSize + 1},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := tC.rules.Check()
+			if tC.fits && err != nil {
+				t.Fatalf("got error %v for rules that can be played", err)
+			}
+			if !tC.fits {
+				if err == nil {
+					t.Error("got no error for rules that can't be played")
+				}
+				return
+			}
+			// valid rules must never panic, however hard the fleet is to place.
+			RandomBoard(rand.New(rand.NewSource(1)), tC.rules)
+		})
+	}
+
+	if _, ok := NewUniformGenerator(testCases[1].rules).Try(rand.New(rand.NewSource(0)), uniformCheckTries); ok {
+		t.Error("sampling found a layout of the tight shapes; the test needs a tighter fleet")
+	}
+}
+
+func TestSpecialWeapons(t *testing.T) {
+	// a patrol boat from c3 to c4, and a destroyer from f6 to f8
+	startBoard := func() Board {
+		var b Board
+		b[2][2] = ShipPatrolBoat
+		b[3][2] = ShipPatrolBoat
+		b[5][5] = ShipDestroyer
+		b[6][5] = ShipDestroyer
+		b[7][5] = ShipDestroyer
+		return b
+	}()
+
+	t.Run("sonar", func(t *testing.T) {
+		board := startBoard
+		if !board.OpponentSonar(1, 1) {
+			t.Fatal("sonar missed a ship in the corner of its area")
+		}
+		if board.OpponentSonar(0, 8) {
+			t.Fatal("sonar found a ship where there was none")
+		}
+	})
+
+	t.Run("airstrike", func(t *testing.T) {
+		board := startBoard
+		board.OpponentShot(2, 2)
+		shots := board.OpponentAirstrike(3, 2)
+		if len(shots) != 4 {
+			t.Fatalf("wanted 4 shots, skipping the one already taken, got %v", shots)
+		}
+		if hit, sunk := Result(shots); !hit || sunk != ShipPatrolBoat {
+			t.Fatalf("wanted the patrol boat sunk, got %v", shots)
+		}
+	})
+
+	t.Run("torpedo", func(t *testing.T) {
+		board := startBoard
+		shot, ok := board.OpponentTorpedo(5, true)
+		if !ok || shot.X != 7 || !shot.Hit {
+			t.Fatalf("wanted hit at f8, got %v, %v", shot, ok)
+		}
+		shot, ok = board.OpponentTorpedo(5, true)
+		if !ok || shot.X != 6 {
+			t.Fatalf("wanted second torpedo to pass the first hit, got %v, %v", shot, ok)
+		}
+		if _, ok := board.OpponentTorpedo(0, false); ok {
+			t.Fatal("torpedo hit something in an empty row")
+		}
+	})
+}
+
+// watcher is a Player that records the fire it's told of.
+type watcher struct {
+	board    Board
+	incoming []IncomingFire
+}
+
+func (w *watcher) GetBoard() *Board               { return &w.board }
+func (w *watcher) Turn(Link) (bool, error)        { return false, nil }
+func (w *watcher) IncomingFire(fire IncomingFire) { w.incoming = append(w.incoming, fire) }
+
+func TestIncomingFire(t *testing.T) {
+	// a patrol boat from c3 to c4, and a destroyer from f6 to f8
+	w := &watcher{}
+	w.board[2][2] = ShipPatrolBoat
+	w.board[3][2] = ShipPatrolBoat
+	w.board[5][5] = ShipDestroyer
+	w.board[6][5] = ShipDestroyer
+	w.board[7][5] = ShipDestroyer
+	link := NewLocalLink(w)
+
+	link.TakeShot(0, 0)
+	link.Airstrike(3, 2)
+	link.Torpedo(5, true)
+	link.Sonar(8, 8)
+	want := []IncomingFire{
+		{Shot: Shot{X: 0, Y: 0}},
+		{Shot: Shot{X: 1, Y: 2}, Weapon: WeaponAirstrike},
+		{Shot: Shot{X: 2, Y: 2, Hit: true}, Ship: ShipPatrolBoat, Weapon: WeaponAirstrike},
+		{Shot: Shot{X: 3, Y: 2, Hit: true, Sunk: ShipPatrolBoat}, Ship: ShipPatrolBoat, Weapon: WeaponAirstrike},
+		{Shot: Shot{X: 4, Y: 2}, Weapon: WeaponAirstrike},
+		{Shot: Shot{X: 5, Y: 2}, Weapon: WeaponAirstrike},
+		{Shot: Shot{X: 7, Y: 5, Hit: true}, Ship: ShipDestroyer, Weapon: WeaponTorpedo},
+		{Shot: Shot{X: 8, Y: 8}, Weapon: WeaponSonar},
+	}
+	if len(w.incoming) != len(want) {
+		t.Fatalf("got fire %v, want %v", w.incoming, want)
+	}
+	for i := range want {
+		if w.incoming[i] != want[i] {
+			t.Errorf("got fire %v, want %v", w.incoming[i], want[i])
+		}
+	}
+
+	// only the airstrike and torpedo are highlighted; the first miss and the sonar ping aren't drawn at all.
+	rows := strings.Split(w.board.FormatIncoming(ClassicFleet, w.incoming[1:]), "\n")
+	for _, tC := range []struct {
+		desc string
+		row  string
+		want string
+	}{
+		{desc: "airstrike", row: rows[len(rows)-4], want: "C   * !-! * *"},
+		{desc: "torpedo", row: rows[len(rows)-7], want: "F           D-D-!"},
+		{desc: "first miss", row: rows[len(rows)-2], want: "A"},
+		{desc: "sonar", row: rows[len(rows)-10], want: "I"},
+	} {
+		if got := strings.TrimRight(tC.row, " "); got != tC.want {
+			t.Errorf("%v: got row %q, want %q", tC.desc, got, tC.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package battleship
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Boards are saved as text in two grids, drawn as Format draws them; rows J to A from top to bottom, and columns 1 to 10.
+// Each position is a token, separated by spaces. Blank lines, and lines starting with #, are ignored.
+//
+// The first grid, headed "shots", is what the player knows of their opponent's board.
+// Its tokens are made of the characters
+//  . nothing known
+//  o shot, and missed
+//  x shot, and hit
+//  ~ known to be water without being shot
+//  ? a sonar ping found a ship near here
+// i.e. "o?" is a miss where a sonar ping found a ship nearby.
+//
+// The second grid, headed "fleet", is the player's own board.
+// Its tokens are the number of the ship at the position, its place in the fleet starting from 1, or . for water,
+// followed by x if the opponent has shot there. i.e. "3x" is a hit on the third ship.
+//
+// i.e. the fleet row with the third ship at e4 to e6, hit at e5, and a miss by the opponent at e1, is
+//  E  .x  .   .   3   3x  3   .   .   .   .
+
+// Section headings of the board text format.
+const (
+	boardTextShots = "shots"
+	boardTextFleet = "fleet"
+)
+
+// boardTextHeader is the column numbers above each grid of the board text format.
+var boardTextHeader = func() string {
+	var sb strings.Builder
+	sb.WriteString(" ")
+	for x := 0; x < BoardSize; x++ {
+		fmt.Fprintf(&sb, "  %-2v", x+1)
+	}
+	return strings.TrimRight(sb.String(), " ")
+}()
+
+// MarshalText implements encoding.TextMarshaler, writing the board in the format described above.
+func (b Board) MarshalText() ([]byte, error) {
+	shots, fleet := b.textRows()
+	var buf bytes.Buffer
+	for _, section := range []struct {
+		name string
+		rows []string
+	}{
+		{boardTextShots, shots},
+		{boardTextFleet, fleet},
+	} {
+		fmt.Fprintln(&buf, section.name)
+		fmt.Fprintln(&buf, boardTextHeader)
+		for i, row := range section.rows {
+			fmt.Fprintf(&buf, "%c  %v\n", 'A'+BoardSize-1-i, row)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, reading a board in the format described above.
+func (b *Board) UnmarshalText(text []byte) error {
+	var section string
+	rows := make(map[string][]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 0 || strings.HasPrefix(fields[0], "#"):
+			continue
+		case len(fields) == 1 && (fields[0] == boardTextShots || fields[0] == boardTextFleet):
+			section = fields[0]
+			if rows[section] != nil {
+				return fmt.Errorf("line %v: second %v grid", line, section)
+			}
+			rows[section] = []string{}
+		case fields[0] == "1":
+			// column numbers
+		case section == "":
+			return fmt.Errorf("line %v: expected %q or %q", line, boardTextShots, boardTextFleet)
+		default:
+			want := string(rune('A' + BoardSize - 1 - len(rows[section])))
+			if len(rows[section]) == BoardSize || !strings.EqualFold(fields[0], want) {
+				return fmt.Errorf("line %v: expected row %v of the %v grid", line, want, section)
+			}
+			rows[section] = append(rows[section], strings.Join(fields[1:], " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return b.fromTextRows(rows[boardTextShots], rows[boardTextFleet])
+}
+
+// boardJSON is the JSON form of a board; the rows of each grid of the text format, from row J to A, without the row letters.
+type boardJSON struct {
+	Shots []string `json:"shots"`
+	Fleet []string `json:"fleet"`
+}
+
+// MarshalJSON implements json.Marshaler, writing the board as the rows of the text format.
+func (b Board) MarshalJSON() ([]byte, error) {
+	var bj boardJSON
+	bj.Shots, bj.Fleet = b.textRows()
+	return json.Marshal(bj)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, reading a board written with MarshalJSON.
+func (b *Board) UnmarshalJSON(data []byte) error {
+	var bj boardJSON
+	if err := json.Unmarshal(data, &bj); err != nil {
+		return err
+	}
+	return b.fromTextRows(bj.Shots, bj.Fleet)
+}
+
+// textRows returns the rows of each grid of the text format, from row J to A, without the row letters.
+func (b *Board) textRows() (shots, fleet []string) {
+	for y := BoardSize - 1; y >= 0; y-- {
+		var shotRow, fleetRow []string
+		for x := 0; x < BoardSize; x++ {
+			var shot string
+			switch {
+			case b[x][y]&playerHit > 0:
+				shot = "x"
+			case b[x][y]&playerShot > 0:
+				shot = "o"
+			}
+			if b[x][y]&playerWater > 0 {
+				shot += "~"
+			}
+			if b[x][y]&playerContact > 0 {
+				shot += "?"
+			}
+			if shot == "" {
+				shot = "."
+			}
+			shotRow = append(shotRow, fmt.Sprintf("%-3v", shot))
+
+			ship := "."
+			if b[x][y]&shipMask > 0 {
+				ship = strconv.Itoa(int(b[x][y] >> 5))
+			}
+			if b[x][y]&opponentHit > 0 {
+				ship += "x"
+			}
+			fleetRow = append(fleetRow, fmt.Sprintf("%-3v", ship))
+		}
+		shots = append(shots, strings.TrimRight(strings.Join(shotRow, " "), " "))
+		fleet = append(fleet, strings.TrimRight(strings.Join(fleetRow, " "), " "))
+	}
+	return
+}
+
+// fromTextRows sets the board from the rows of each grid of the text format.
+// The board is only changed if the rows are valid.
+func (b *Board) fromTextRows(shots, fleet []string) error {
+	if len(shots) != BoardSize || len(fleet) != BoardSize {
+		return fmt.Errorf("got %v rows of shots and %v of fleet, want %v of each", len(shots), len(fleet), BoardSize)
+	}
+
+	var nb Board
+	for i := 0; i < BoardSize; i++ {
+		y := BoardSize - 1 - i
+		shotTokens, fleetTokens := strings.Fields(shots[i]), strings.Fields(fleet[i])
+		if len(shotTokens) != BoardSize || len(fleetTokens) != BoardSize {
+			return fmt.Errorf("row %c: got %v shots and %v fleet positions, want %v of each", 'A'+y, len(shotTokens), len(fleetTokens), BoardSize)
+		}
+
+		for x := 0; x < BoardSize; x++ {
+			for _, c := range shotTokens[x] {
+				switch c {
+				case '.':
+				case 'o':
+					nb[x][y] |= playerShot
+				case 'x':
+					nb[x][y] |= playerShot | playerHit
+				case '~':
+					nb[x][y] |= playerWater
+				case '?':
+					nb[x][y] |= playerContact
+				default:
+					return fmt.Errorf("%v: unknown shot %q", FormatPosition(x, y), shotTokens[x])
+				}
+			}
+
+			token := fleetTokens[x]
+			if strings.HasSuffix(token, "x") {
+				nb[x][y] |= opponentHit
+				token = strings.TrimSuffix(token, "x")
+			}
+			if token == "." {
+				continue
+			}
+			ship, err := strconv.Atoi(token)
+			if err != nil || ship < 1 || ship > MaxFleetSize {
+				return fmt.Errorf("%v: unknown ship %q; want . or 1 to %v", FormatPosition(x, y), fleetTokens[x], MaxFleetSize)
+			}
+			nb[x][y] |= byte(ship) << 5
+		}
+	}
+
+	*b = nb
+	return nil
+}
@@ -0,0 +1,152 @@
+package battleship
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// testBoardText is a board with a destroyer at e4 to e6, hit at e5, a miss by the opponent at e1,
+// and the player having missed at a1, hit at b2, and found water at c3.
+const testBoardText = `
+# a test board
+shots
+   1   2   3   4   5   6   7   8   9   10
+J  .   .   .   .   .   .   .   .   .   .
+I  .   .   .   .   .   .   .   .   .   .
+H  .   .   .   .   .   .   .   .   .   .
+G  .   .   .   .   .   .   .   .   .   .
+F  .   .   .   .   .   .   .   .   .   .
+E  .   .   .   .   .   .   .   .   .   .
+D  .   .   .   .   .   .   .   .   .   .
+C  .   .   ~   .   .   .   .   .   .   .
+B  .   x   .   .   .   .   .   .   .   .
+A  o   .   .   .   .   .   .   .   .   .
+
+fleet
+   1   2   3   4   5   6   7   8   9   10
+J  .   .   .   .   .   .   .   .   .   .
+I  .   .   .   .   .   .   .   .   .   .
+H  .   .   .   .   .   .   .   .   .   .
+G  .   .   .   .   .   .   .   .   .   .
+F  .   .   .   .   .   .   .   .   .   .
+E  .x  .   .   3   3x  3   .   .   .   .
+D  .   .   .   .   .   .   .   .   .   .
+C  .   .   .   .   .   .   .   .   .   .
+B  .   .   .   .   .   .   .   .   .   .
+A  .   .   .   .   .   .   .   .   .   .
+`
+
+func TestBoardText(t *testing.T) {
+	var want Board
+	want[3][4] = ShipDestroyer
+	want[4][4] = ShipDestroyer | opponentHit
+	want[5][4] = ShipDestroyer
+	want[0][4] = opponentHit
+	want[0][0] = playerShot
+	want[1][1] = playerShot | playerHit
+	want[2][2] = playerWater
+
+	var b Board
+	if err := b.UnmarshalText([]byte(testBoardText)); err != nil {
+		t.Fatal(err)
+	}
+	if b != want {
+		t.Errorf("got board\n%v\nwant\n%v", b, want)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for game := 0; game < 100; game++ {
+		b := RandomBoard(rng, Rules{Fleet: ShapesFleet})
+		for shot := 0; shot < 40; shot++ {
+			x, y := rng.Intn(BoardSize), rng.Intn(BoardSize)
+			hit, _ := b.OpponentShot(x, y)
+			b.PlayerShot(x, y, hit)
+			if shot%10 == 0 {
+				b.PlayerSonar(x, y, shot%20 == 0)
+			}
+		}
+
+		text, err := b.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromText Board
+		if err := fromText.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if fromText != b {
+			t.Fatalf("board changed writing to text and back;\n%s", text)
+		}
+
+		data, err := json.Marshal(struct{ Board Board }{b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromJSON struct{ Board Board }
+		if err := json.Unmarshal(data, &fromJSON); err != nil {
+			t.Fatal(err)
+		}
+		if fromJSON.Board != b {
+			t.Fatalf("board changed writing to JSON and back;\n%s", data)
+		}
+	}
+}
+
+func TestBoardTextErrors(t *testing.T) {
+	testCases := []struct {
+		desc string
+		edit func(text string) string
+	}{
+		{
+			desc: "no fleet",
+			edit: func(text string) string {
+				return text[:strings.Index(text, "fleet")]
+			},
+		},
+		{
+			desc: "unknown ship",
+			edit: func(text string) string {
+				return strings.Replace(text, "3x", "9x", 1)
+			},
+		},
+		{
+			desc: "unknown shot",
+			edit: func(text string) string {
+				return strings.Replace(text, "~", "*", 1)
+			},
+		},
+		{
+			desc: "missing position",
+			edit: func(text string) string {
+				return strings.Replace(text, "A  o   .", "A  o", 1)
+			},
+		},
+		{
+			desc: "rows out of order",
+			edit: func(text string) string {
+				return strings.Replace(text, "B  .   x", "D  .   x", 1)
+			},
+		},
+		{
+			desc: "no heading",
+			edit: func(text string) string {
+				return strings.Replace(text, "shots\n", "", 1)
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			b := Board{}
+			b[9][9] = ShipCarrier
+			before := b
+			if err := b.UnmarshalText([]byte(tC.edit(testBoardText))); err == nil {
+				t.Error("no error reading broken board")
+			}
+			if b != before {
+				t.Error("board changed after an error reading it")
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+// Package bot plays external programs as battleship players, talking the line based protocol over their stdin and stdout.
+package bot
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stewi1014/battleship"
+)
+
+// The bot protocol lets programs written in any language play battleship, by talking to it over stdin and stdout.
+// Every message is a single line of space separated words, and positions are written as they are typed in the terminal, i.e. "b6".
+// The messages are described in the README.
+
+// botProtocolVersion is the version of the protocol described above.
+const botProtocolVersion = 2
+
+// botRetries is how many invalid replies a bot can make in a row before it is given up on.
+const botRetries = 10
+
+// NewBotPlayer launches the external bot given by command, introducing it to the game and having it place its ships.
+// If the bot takes longer than timeout to reply to anything, or exits, it is given up on and an error is returned.
+func NewBotPlayer(command []string, rules battleship.Rules, timeout time.Duration) (*BotPlayer, error) {
+	if len(command) == 0 {
+		return nil, errors.New("no bot command given")
+	}
+	if rules.Arsenal != (battleship.Arsenal{}) {
+		return nil, errors.New("bots can't play with special weapons")
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	bp := &BotPlayer{
+		board:   rules.NewBoard(),
+		rules:   rules,
+		cmd:     cmd,
+		stdin:   stdin,
+		lines:   make(chan string),
+		done:    make(chan struct{}),
+		timeout: timeout,
+	}
+	go bp.readLines(stdout)
+
+	if err := bp.setUp(); err != nil {
+		bp.Close()
+		return nil, err
+	}
+	return bp, nil
+}
+
+// BotPlayer is a Player driving an external bot program over the bot protocol.
+type BotPlayer struct {
+	board battleship.Board
+	score int
+	rules battleship.Rules
+
+	// Name is the name the bot gave itself.
+	Name string
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	lines   chan string
+	timeout time.Duration
+
+	// incomingErr is the first error telling the bot of incoming fire, reported on its next turn.
+	incomingErr error
+
+	// done is closed when the bot is closed, after which nothing more is read from lines.
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// readLines sends lines written by the bot to bp.lines, closing it once the bot's output is closed.
+// Once the bot is closed, lines are read and thrown away, so the bot isn't blocked writing them.
+func (bp *BotPlayer) readLines(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		select {
+		case bp.lines <- scanner.Text():
+		case <-bp.done:
+		}
+	}
+	close(bp.lines)
+}
+
+// send writes a line to the bot.
+func (bp *BotPlayer) send(format string, a ...interface{}) error {
+	_, err := fmt.Fprintf(bp.stdin, format+"\n", a...)
+	if err != nil {
+		return fmt.Errorf("bot crashed: %v", err)
+	}
+	return nil
+}
+
+// receive waits for a line from the bot starting with the word want, and returns the remaining words.
+func (bp *BotPlayer) receive(want string) ([]string, error) {
+	select {
+	case line, ok := <-bp.lines:
+		if !ok {
+			return nil, errors.New("bot crashed: it stopped talking")
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 || args[0] != want {
+			return nil, fmt.Errorf("bot said %q, wanted %q", line, want)
+		}
+		return args[1:], nil
+	case <-time.After(bp.timeout):
+		return nil, fmt.Errorf("bot took longer than %v to reply", bp.timeout)
+	}
+}
+
+// setUp does the handshake, describes the rules, and has the bot place its ships.
+func (bp *BotPlayer) setUp() error {
+	if err := bp.send("battleship %v", botProtocolVersion); err != nil {
+		return err
+	}
+	args, err := bp.receive("ready")
+	if err != nil {
+		return err
+	}
+	bp.Name = strings.Join(args, " ")
+
+	var noTouching, diagonal int
+	if bp.rules.NoTouching {
+		noTouching = 1
+	}
+	if bp.rules.Diagonal {
+		diagonal = 1
+	}
+	err = bp.send("rules size %v notouching %v diagonal %v bonus %v", bp.rules.GetSize(), noTouching, diagonal, battleship.BonusNames[bp.rules.BonusShot])
+	if err != nil {
+		return err
+	}
+
+	fleet := bp.rules.GetFleet()
+	for i, class := range fleet {
+		cells := make([]string, len(class.Cells))
+		for j, cell := range class.Cells {
+			cells[j] = fmt.Sprintf("%v,%v", cell.X, cell.Y)
+		}
+		name := strings.Replace(class.Name, " ", "_", -1)
+		if err := bp.send("ship %v %c %v %v", i+1, class.Symbol, name, strings.Join(cells, " ")); err != nil {
+			return err
+		}
+	}
+
+	if err := bp.send("place"); err != nil {
+		return err
+	}
+	for _, ship := range fleet.Ships() {
+		if err := bp.placeShip(ship); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// placeShip reads placements from the bot until one for the given ship is valid.
+func (bp *BotPlayer) placeShip(ship byte) error {
+	for tries := 0; tries < botRetries; tries++ {
+		args, err := bp.receive("place")
+		if err != nil {
+			return err
+		}
+		if len(args) != 2 && (len(args) != 3 || args[2] != "mirror") {
+			if err := bp.send("error want [location] [direction]"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		x, y, err := battleship.ParsePosition(args[0])
+		direction, ok := battleship.DirectionNames[args[1]]
+		if err == nil && !ok {
+			err = fmt.Errorf("unknown direction %v", args[1])
+		}
+		if err == nil {
+			if len(args) == 3 {
+				direction |= battleship.Mirrored
+			}
+			err = bp.rules.PlaceShip(&bp.board, x, y, direction, ship)
+		}
+		if err != nil {
+			if err := bp.send("error %v", err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		return bp.send("ok")
+	}
+	return errors.New("bot couldn't place its ships")
+}
+
+// GetBoard implements Player.
+func (bp *BotPlayer) GetBoard() *battleship.Board {
+	return &bp.board
+}
+
+// IncomingFire implements Player.
+// The bot is told where the opponent's shot landed, and which of its ships it hit.
+// Bots play without special weapons, so are never pinged with sonar.
+func (bp *BotPlayer) IncomingFire(fire battleship.IncomingFire) {
+	position := battleship.FormatPosition(fire.X, fire.Y)
+	var err error
+	switch {
+	case fire.Weapon == battleship.WeaponSonar:
+	case fire.Sunk != 0:
+		err = bp.send("incoming %v hit sunk %v", position, fire.Ship>>5)
+	case fire.Hit:
+		err = bp.send("incoming %v hit %v", position, fire.Ship>>5)
+	default:
+		err = bp.send("incoming %v miss", position)
+	}
+	if bp.incomingErr == nil {
+		bp.incomingErr = err
+	}
+}
+
+// Turn implements Player.
+// If telling the bot of incoming fire failed, that error is returned instead.
+func (bp *BotPlayer) Turn(remote battleship.Link) (won bool, err error) {
+	if bp.incomingErr != nil {
+		return false, bp.incomingErr
+	}
+	for {
+		x, y, err := bp.askShot()
+		if err != nil {
+			return false, err
+		}
+
+		hit, sunk := remote.TakeShot(x, y)
+		bp.board.PlayerShot(x, y, hit)
+		switch {
+		case sunk != 0:
+			bp.score++
+			err = bp.send("result %v hit sunk %v", battleship.FormatPosition(x, y), sunk>>5)
+		case hit:
+			err = bp.send("result %v hit", battleship.FormatPosition(x, y))
+		default:
+			err = bp.send("result %v miss", battleship.FormatPosition(x, y))
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if bp.rules.Won(bp.score) || !bp.rules.ShootAgain(hit, sunk) {
+			return bp.rules.Won(bp.score), nil
+		}
+	}
+}
+
+// askShot asks the bot for a shot, until it gives a valid one.
+func (bp *BotPlayer) askShot() (x, y int, err error) {
+	if err := bp.send("shoot"); err != nil {
+		return 0, 0, err
+	}
+	for tries := 0; tries < botRetries; tries++ {
+		args, err := bp.receive("shot")
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(args) != 1 {
+			if err := bp.send("error want [location]"); err != nil {
+				return 0, 0, err
+			}
+			continue
+		}
+
+		x, y, err = battleship.ParsePosition(args[0])
+		if err == nil && !bp.rules.InBounds(x, y) {
+			err = errors.New("off the board")
+		}
+		if err == nil && bp.board.PlayerHasShot(x, y) {
+			err = errors.New("already shot")
+		}
+		if err != nil {
+			if err := bp.send("error %v", err); err != nil {
+				return 0, 0, err
+			}
+			continue
+		}
+		return x, y, nil
+	}
+	return 0, 0, errors.New("bot couldn't take a valid shot")
+}
+
+// GameOver implements GameEnder.
+// The bot is told if it won, and then closed.
+func (bp *BotPlayer) GameOver(won bool, opponent *battleship.Board) error {
+	result := "loss"
+	if won {
+		result = "win"
+	}
+	err := bp.send("gameover %v", result)
+	bp.Close()
+	return err
+}
+
+// Close stops the bot, giving it a moment to exit by itself first.
+// Closing it again does nothing.
+func (bp *BotPlayer) Close() error {
+	bp.closeOnce.Do(func() {
+		bp.stdin.Close()
+		close(bp.done)
+
+		// the bot's output must all be read before waiting for it to exit.
+		drained := make(chan struct{})
+		go func() {
+			for range bp.lines {
+			}
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-time.After(bp.timeout):
+			bp.closeErr = bp.cmd.Process.Kill()
+			<-drained
+		}
+		bp.cmd.Wait()
+	})
+	return bp.closeErr
+}
+
+// ParseBotCommand splits a bot command line into the program and its arguments.
+// Arguments are separated by spaces, and can be quoted with double quotes to include spaces.
+func ParseBotCommand(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	var quoted, inArg bool
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case r == ' ' && !quoted:
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote in bot command")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
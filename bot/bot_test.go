@@ -0,0 +1,289 @@
+package bot
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stewi1014/battleship"
+	"github.com/stewi1014/battleship/ai"
+)
+
+// testBotEnv is set to have the test binary run as a bot, instead of running tests.
+const testBotEnv = "BATTLESHIP_TEST_BOT"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(testBotEnv); mode != "" {
+		// we've been started by TestBotPlayer, to play as a bot.
+		runTestBot(mode)
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// runTestBot plays as a bot over stdin and stdout, behaving as given by mode.
+// "good" plays properly, "crash" exits straight away, "slow" never replies, "overlap" places every ship in the same place,
+// "chatty" plays properly, but says goodbye at length once the game is over,
+// and "edge" shoots just off each edge of the board, exiting if either shot is accepted, and then at the far corner.
+// If it's told of incoming fire that doesn't fit where its ships are, it exits.
+func runTestBot(mode string) {
+	input := bufio.NewScanner(os.Stdin)
+	var ships, shot, size int
+	var shooting bool
+	// lengths holds the length of each ship, as placed along every other row from a1.
+	var lengths []int
+	for input.Scan() {
+		args := strings.Fields(input.Text())
+		switch {
+		case mode == "crash":
+			os.Exit(1)
+		case mode == "slow":
+			continue
+		case args[0] == "battleship":
+			fmt.Println("ready test bot")
+		case args[0] == "rules":
+			size, _ = strconv.Atoi(args[2])
+		case mode == "edge" && (args[0] == "shoot" || args[0] == "error"):
+			edges := []string{battleship.FormatPosition(size, 0), battleship.FormatPosition(0, size), battleship.FormatPosition(size-1, size-1)}
+			if shot == len(edges) {
+				fmt.Fprintln(os.Stderr, "test bot: shot at the far corner refused:", input.Text())
+				os.Exit(1)
+			}
+			fmt.Println("shot", edges[shot])
+			shot++
+		case mode == "edge" && args[0] == "result":
+			if shot < 3 {
+				fmt.Fprintln(os.Stderr, "test bot: shot off the board accepted:", input.Text())
+				os.Exit(1)
+			}
+			return
+		case args[0] == "ship":
+			ships++
+			lengths = append(lengths, len(args)-4)
+		case args[0] == "incoming":
+			x, y, err := battleship.ParsePosition(args[1])
+			var ship int
+			if err == nil && y%2 == 0 && y/2 < ships && x < lengths[y/2] {
+				ship = y/2 + 1
+			}
+			if err != nil || args[2] == "miss" != (ship == 0) || ship != 0 && args[len(args)-1] != fmt.Sprint(ship) {
+				fmt.Fprintln(os.Stderr, "test bot: wrong incoming fire:", input.Text())
+				os.Exit(1)
+			}
+		case args[0] == "place":
+			for i := 0; i < ships; i++ {
+				row := 2 * i
+				if mode == "overlap" {
+					row = 0
+				}
+				fmt.Printf("place %c1 right\n", 'a'+row)
+			}
+		case args[0] == "shoot", args[0] == "error" && shooting:
+			// shots rejected, as when they're off a small board, are followed by the next.
+			shooting = true
+			fmt.Println("shot", battleship.FormatPosition(shot%battleship.BoardSize, shot/battleship.BoardSize))
+			shot++
+		case args[0] == "result":
+			shooting = false
+		case args[0] == "gameover":
+			if mode == "chatty" {
+				for i := 0; i < 100; i++ {
+					fmt.Println("goodbye")
+				}
+			}
+			return
+		}
+	}
+}
+
+func TestBotPlayer(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		mode    string
+		rules   battleship.Rules
+		wantErr bool
+	}{
+		{
+			desc: "plays a game",
+			mode: "good",
+		},
+		{
+			desc:  "plays on a small board",
+			mode:  "good",
+			rules: battleship.Rules{Fleet: battleship.SmallFleet, Size: 5},
+		},
+		{
+			desc:    "crashes",
+			mode:    "crash",
+			wantErr: true,
+		},
+		{
+			desc:    "times out",
+			mode:    "slow",
+			wantErr: true,
+		},
+		{
+			desc:    "places ships badly",
+			mode:    "overlap",
+			wantErr: true,
+		},
+		{
+			desc: "talks after the game",
+			mode: "chatty",
+		},
+		{
+			desc:    "special weapons",
+			mode:    "good",
+			rules:   battleship.Rules{Arsenal: battleship.AdvancedArsenal},
+			wantErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			os.Setenv(testBotEnv, tC.mode)
+			defer os.Unsetenv(testBotEnv)
+
+			bot, err := NewBotPlayer([]string{os.Args[0]}, tC.rules, time.Second)
+			if tC.wantErr {
+				if err == nil {
+					bot.Close()
+					t.Fatal("wanted error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bot.Name != "test bot" {
+				t.Errorf("got name %q, want %q", bot.Name, "test bot")
+			}
+
+			players := []battleship.Player{bot, ai.NewAI(tC.rules)}
+			for turn := 0; ; turn++ {
+				player, opponent := players[turn%2], players[(turn+1)%2]
+				won, err := player.Turn(battleship.NewLocalLink(opponent))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if won {
+					if err := battleship.GameOver(player, opponent); err != nil {
+						t.Fatal(err)
+					}
+					break
+				}
+			}
+
+			// the game being over closed the bot, and everything it said has been read.
+			select {
+			case line, ok := <-bot.lines:
+				if ok {
+					t.Errorf("bot output %q left unread after closing", line)
+				}
+			case <-time.After(time.Second):
+				t.Error("bot output still being read after closing")
+			}
+
+			for x := 0; x < battleship.BoardSize; x++ {
+				for y := 0; y < battleship.BoardSize; y++ {
+					if !tC.rules.InBounds(x, y) && bot.board.PlayerHasShot(x, y) {
+						t.Errorf("bot shot %v, off the board", battleship.FormatPosition(x, y))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBotPlayerEdges(t *testing.T) {
+	for _, size := range []int{1, 5, battleship.BoardSize} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			os.Setenv(testBotEnv, "edge")
+			defer os.Unsetenv(testBotEnv)
+
+			dinghy := battleship.ShipClass{Name: "Dinghy", Symbol: 'G', Cells: battleship.Line(1)}
+			rules := battleship.Rules{Fleet: battleship.Fleet{dinghy}, Size: size}
+			bot, err := NewBotPlayer([]string{os.Args[0]}, rules, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bot.Close()
+
+			if _, err := bot.Turn(battleship.NewLocalLink(ai.NewAI(rules))); err != nil {
+				t.Fatal(err)
+			}
+			if !bot.board.PlayerHasShot(size-1, size-1) {
+				t.Errorf("bot's shot at the far corner, %v, wasn't taken", battleship.FormatPosition(size-1, size-1))
+			}
+		})
+	}
+}
+
+func TestBotPlayerIncomingCrash(t *testing.T) {
+	os.Setenv(testBotEnv, "good")
+	defer os.Unsetenv(testBotEnv)
+
+	bot, err := NewBotPlayer([]string{os.Args[0]}, battleship.Rules{}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.Close()
+
+	// the bot dies during the opponent's turn.
+	if err := bot.cmd.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	bot.cmd.Wait()
+	bot.IncomingFire(battleship.IncomingFire{Shot: battleship.Shot{X: 9, Y: 9}})
+	if bot.incomingErr == nil {
+		t.Fatal("telling a dead bot of incoming fire didn't fail")
+	}
+	if _, err := bot.Turn(battleship.NewLocalLink(ai.NewAI(battleship.Rules{}))); err != bot.incomingErr {
+		t.Errorf("turn failed with %v, want the error telling it of incoming fire, %v", err, bot.incomingErr)
+	}
+}
+
+func TestParseBotCommand(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			desc: "plain",
+			line: "python3 bot.py --fast",
+			want: []string{"python3", "bot.py", "--fast"},
+		},
+		{
+			desc: "quoted",
+			line: `"my bots/bot" -name "big  guns"`,
+			want: []string{"my bots/bot", "-name", "big  guns"},
+		},
+		{
+			desc: "empty quotes",
+			line: `bot ""`,
+			want: []string{"bot", ""},
+		},
+		{
+			desc:    "unterminated",
+			line:    `bot "oops`,
+			wantErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := ParseBotCommand(tC.line)
+			if (err != nil) != tC.wantErr {
+				t.Fatalf("got error %v, wanted error %v", err, tC.wantErr)
+			}
+			if strings.Join(got, "|") != strings.Join(tC.want, "|") || len(got) != len(tC.want) {
+				t.Errorf("got %q, want %q", got, tC.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,533 @@
+// Battleship is a two player battleship game for the terminal, between people, AIs and external bots.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/stewi1014/battleship"
+	"github.com/stewi1014/battleship/ai"
+	"github.com/stewi1014/battleship/bot"
+	"github.com/stewi1014/battleship/server"
+	"github.com/stewi1014/battleship/terminal"
+	"github.com/stewi1014/battleship/tournament"
+)
+
+// gameRules are the rules both players play by, as set by flags.
+var gameRules battleship.Rules
+
+// advanced gives both players the advanced special weapons.
+var advanced bool
+
+// bonusShot is the name of the bonus shot rule, parsed into gameRules after flags are parsed.
+var bonusShot string
+
+// samplerBudget is how long the sampler AI spends thinking about each shot.
+var samplerBudget time.Duration
+
+// difficulty is the name of the AI difficulty, parsed into aiDifficulty after flags are parsed.
+var difficulty string
+var aiDifficulty int
+
+// historyPath is where the learning AI keeps what it knows about its opponents.
+// It is loaded into history when the first learner is created, which every learner then shares,
+// so one saving doesn't overwrite what another has learnt.
+var historyPath string
+var history *battleship.History
+
+// botTimeout is how long external bots have to reply before they're given up on.
+var botTimeout time.Duration
+
+// noAnalysis skips the analysis of each player's shots after the game.
+var noAnalysis bool
+
+// hideAI hides the AIs' boards during gameplay.
+var hideAI bool
+
+// verbose has AIs explain each attack they make, and trace is a file to write the explanations to instead of the terminal.
+// The explanations are written to traceOutput.
+var verbose bool
+var tracePath string
+var traceOutput io.Writer
+
+// fleet is the name of, or path to, the fleet to play with, loaded into gameRules after flags are parsed.
+var fleet string
+
+// aiParamsPath is a file of tuned parameters for the classic AI, loaded into aiParams after flags are parsed.
+var aiParamsPath string
+var aiParams = ai.DefaultAIParams()
+
+// bookPath is an opening book for the classic AI, from which the opening for gameRules is loaded into aiOpening after flags are parsed.
+var bookPath string
+var aiOpening []battleship.Point
+
+func init() {
+	flag.BoolVar(&hideAI, "no-show-ai", false, "no-show-ai hides the AI's board during gameplay")
+	flag.BoolVar(&gameRules.NoTouching, "no-touching", false, "no-touching forbids ships from touching eachother, even diagonally")
+	flag.IntVar(&gameRules.Size, "size", battleship.BoardSize, "size plays on only the bottom left size x size positions of the board")
+	flag.BoolVar(&gameRules.Diagonal, "diagonal", false, "diagonal allows straight ships to be placed diagonally")
+	flag.BoolVar(&noAnalysis, "no-analysis", false, "no-analysis skips the analysis of each player's shots after the game")
+	flag.BoolVar(&verbose, "verbose", false, "verbose has AIs explain each attack they make")
+	flag.StringVar(&tracePath, "trace", "", "trace writes AIs' explanations of their attacks to the given file, rather than the terminal")
+	flag.BoolVar(&advanced, "advanced", false, "advanced gives players limited use sonar pings, airstrikes and torpedoes")
+	flag.StringVar(&fleet, "fleet", "classic", "fleet is the fleet of ships to play with; \"classic\", \"shapes\", \"small\" or the path to a fleet file")
+	flag.DurationVar(&samplerBudget, "sampler-budget", 200*time.Millisecond, "sampler-budget is how long the sampler AI can spend on each shot")
+	flag.StringVar(&difficulty, "difficulty", "easy", "difficulty sets how carefully AIs place their ships; \"easy\", \"normal\" or \"hard\"")
+	flag.StringVar(&aiParamsPath, "ai-params", "", "ai-params is a file of parameters for the classic AI, as written by the tune command")
+	flag.StringVar(&bookPath, "book", "", "book is an opening book for the classic AI to hunt with, as written by the book command")
+	flag.StringVar(&historyPath, "history", battleship.DefaultHistoryPath(), "history is the file the learning AI keeps its opponents' past layouts in")
+	flag.DurationVar(&botTimeout, "bot-timeout", 10*time.Second, "bot-timeout is how long an external bot has to reply before it loses")
+	flag.StringVar(&bonusShot, "bonus-shot", "none", "bonus-shot gives another shot after a \"hit\", or only after a \"sink\"")
+}
+
+func main() {
+	flag.Parse()
+
+	var err error
+	gameRules.BonusShot, err = battleship.ParseBonusShot(bonusShot)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	aiDifficulty, err = battleship.ParseDifficulty(difficulty)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	gameRules.Fleet, err = battleship.LoadFleet(fleet)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if aiParamsPath != "" {
+		aiParams, err = ai.LoadAIParams(aiParamsPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if advanced {
+		gameRules.Arsenal = battleship.AdvancedArsenal
+	}
+	if err := gameRules.Check(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if bookPath != "" && flag.Arg(0) != "book" {
+		book, err := ai.LoadBook(bookPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		var ok bool
+		if aiOpening, ok = book.Lookup(gameRules); !ok {
+			fmt.Printf("%v has no opening for these rules\n", bookPath)
+			os.Exit(1)
+		}
+	}
+
+	switch {
+	case tracePath != "":
+		f, err := os.Create(tracePath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		traceOutput = f
+	case verbose:
+		traceOutput = os.Stdout
+	}
+
+	if flag.Arg(0) == "tournament" {
+		if err := runTournament(flag.Args()[1:], gameRules); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "tune" {
+		if err := runTune(flag.Args()[1:], gameRules); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "book" {
+		if err := runBook(flag.Args()[1:], gameRules); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "nash" {
+		if err := runNash(flag.Args()[1:], gameRules); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "serve-tcp" {
+		if err := runServe(flag.Args()[1:], gameRules); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	input := bufio.NewReader(os.Stdin)
+	player1, player2, err := gameSetup(input, gameRules)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	result, err := battleship.PlayGame(player1, player2, func(player int) {
+		fmt.Printf("Player %v Turn\n", player)
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Player %v Won!\n", result.Winner)
+	if result.Winner == 1 {
+		endGame(player1, player2)
+	} else {
+		endGame(player2, player1)
+	}
+
+	if !noAnalysis {
+		fmt.Println("Analysing the game...")
+		for i, moves := range result.Moves {
+			fmt.Printf("Player %v: %v", i+1, ai.Analyse(gameRules, moves, ai.AnalysisBudget))
+		}
+	}
+}
+
+// endGame lets the players know the game is over.
+func endGame(winner, loser battleship.Player) {
+	if err := battleship.GameOver(winner, loser); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// gameSetup sets up the game as per user preference,
+// returning an AI, TerminalUI, or some combination of the two.
+func gameSetup(input *bufio.Reader, rules battleship.Rules) (p1 battleship.Player, p2 battleship.Player, err error) {
+	fmt.Println("Player 1:")
+	p1, err = askAndCreatePlayer(input, rules)
+	if err != nil {
+		return
+	}
+	fmt.Println("Player 2:")
+	p2, err = askAndCreatePlayer(input, rules)
+	return
+}
+
+// askAndCreatePlayer asks the user what kind of player they want to create, and creates it
+func askAndCreatePlayer(input *bufio.Reader, rules battleship.Rules) (battleship.Player, error) {
+	var err error
+	var str string
+
+	for {
+		fmt.Println("Enter \"ai\", \"sampler\", \"learner\", \"nash\", \"bot\" or \"player\"")
+		str, err = input.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		str = strings.ToLower(strings.TrimSpace(str))
+
+		if str == "ai" {
+			a := ai.NewAI(rules)
+			a.SetDifficulty(aiDifficulty)
+			a.SetParams(aiParams)
+			a.SetOpening(aiOpening)
+			setAIOutput(a)
+			return a, nil
+		}
+		if str == "sampler" {
+			a := ai.NewSamplerAI(rules, samplerBudget)
+			a.SetDifficulty(aiDifficulty)
+			setAIOutput(a)
+			return a, nil
+		}
+		if str == "learner" {
+			return askAndCreateLearner(input, rules)
+		}
+		if str == "nash" {
+			return askAndCreateStrategyAI(input, rules)
+		}
+		if str == "bot" {
+			return askAndCreateBot(input, rules)
+		}
+		if str == "player" {
+			tui := terminal.NewTerminalUI(input, os.Stdout, rules)
+			tui.SetUp()
+			return tui, nil
+		}
+	}
+}
+
+// askAndCreateLearner asks who the learning AI is playing against, and creates it.
+func askAndCreateLearner(input *bufio.Reader, rules battleship.Rules) (battleship.Player, error) {
+	if history == nil {
+		var err error
+		history, err = battleship.LoadHistory(historyPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Println("Enter opponent's name")
+	name, err := input.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	a := ai.NewLearningAI(rules, samplerBudget, history, name)
+	a.SetDifficulty(aiDifficulty)
+	setAIOutput(a)
+	return a, nil
+}
+
+// aiOutput is implemented by AIs that can show their board and explain their attacks.
+type aiOutput interface {
+	SetOutput(output io.Writer)
+	SetTrace(trace io.Writer)
+}
+
+// setAIOutput has an AI show its board on the terminal, unless hideAI is set, and explain its attacks to traceOutput.
+func setAIOutput(a aiOutput) {
+	if !hideAI {
+		a.SetOutput(os.Stdout)
+	}
+	a.SetTrace(traceOutput)
+}
+
+// askAndCreateStrategyAI asks for the strategy file to play by, and creates an AI that plays by it.
+func askAndCreateStrategyAI(input *bufio.Reader, rules battleship.Rules) (battleship.Player, error) {
+	fmt.Println("Enter strategy file")
+	path, err := input.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	strategy, err := ai.LoadStrategy(strings.TrimSpace(path))
+	if err != nil {
+		return nil, err
+	}
+	return ai.NewStrategyAI(rules, strategy)
+}
+
+// askAndCreateBot asks for the command to run an external bot with, and starts it.
+func askAndCreateBot(input *bufio.Reader, rules battleship.Rules) (battleship.Player, error) {
+	fmt.Println("Enter bot command")
+	line, err := input.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	command, err := bot.ParseBotCommand(strings.TrimSpace(line))
+	if err != nil {
+		return nil, err
+	}
+
+	bp, err := bot.NewBotPlayer(command, rules, botTimeout)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Bot %q ready\n", bp.Name)
+	return bp, nil
+}
+
+// runTournament runs the tournament subcommand, given the arguments following it.
+// i.e. battleship tournament -games=20 ai sampler "bot:python3 bot.py"
+func runTournament(args []string, rules battleship.Rules) error {
+	flags := flag.NewFlagSet("tournament", flag.ExitOnError)
+	games := flags.Int("games", 10, "games is how many games each pair of participants plays")
+	asJSON := flags.Bool("json", false, "json writes the results as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: battleship [rule flags] tournament [flags] participants...")
+		fmt.Fprintln(flags.Output(), "Participants are \"ai\", \"sampler\", \"nash:[path]\" or \"bot:[command]\"")
+		fmt.Fprintln(flags.Output(), "Bots can't play with special weapons, so can't enter tournaments played with --advanced")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		return errors.New("a tournament needs at least two participants")
+	}
+
+	t := tournament.Tournament{
+		Rules: rules,
+		Games: *games,
+	}
+	names := make(map[string]int)
+	for _, arg := range flags.Args() {
+		p, err := tournament.ParseParticipant(arg, aiDifficulty, aiParams, aiOpening, samplerBudget, botTimeout)
+		if err != nil {
+			return err
+		}
+		// the same participant can play itself, but needs telling apart.
+		names[p.Name]++
+		if names[p.Name] > 1 {
+			p.Name = fmt.Sprintf("%v #%v", p.Name, names[p.Name])
+		}
+		t.Participants = append(t.Participants, p)
+	}
+
+	result := t.Run(func(played, total int) {
+		fmt.Fprintf(os.Stderr, "\rPlayed %v/%v games", played, total)
+	})
+	fmt.Fprintln(os.Stderr)
+
+	if *asJSON {
+		data, err := json.MarshalIndent(result, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Print(result)
+	return nil
+}
+
+// runServe runs the serve-tcp subcommand, given the arguments following it.
+// i.e. battleship serve-tcp -ai=sampler :4000
+func runServe(args []string, rules battleship.Rules) error {
+	flags := flag.NewFlagSet("serve-tcp", flag.ExitOnError)
+	opponent := flags.String("ai", "ai", "ai is who clients play when they choose the AI; \"ai\", \"sampler\", \"nash:[path]\" or \"bot:[command]\"")
+	turnTimeout := flags.Duration("turn-timeout", server.DefaultTurnTimeout, "turn-timeout is how long clients have to take each turn before they forfeit, and to choose who to play or place their ships before they're disconnected")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: battleship [rule flags] serve-tcp [flags] [address]")
+		fmt.Fprintln(flags.Output(), "i.e. battleship serve-tcp :4000, then connect with nc localhost 4000")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("serve-tcp needs an address to listen on")
+	}
+
+	p, err := tournament.ParseParticipant(*opponent, aiDifficulty, aiParams, aiOpening, samplerBudget, botTimeout)
+	if err != nil {
+		return err
+	}
+	s := &server.Server{
+		Rules:       rules,
+		NewAI:       p.New,
+		Log:         os.Stderr,
+		TurnTimeout: *turnTimeout,
+	}
+	return s.ListenAndServe(flags.Arg(0))
+}
+
+// runNash runs the nash subcommand, given the arguments following it.
+// i.e. battleship --size=5 --fleet=small nash -rounds=1000 -o strategy.json
+func runNash(args []string, rules battleship.Rules) error {
+	flags := flag.NewFlagSet("nash", flag.ExitOnError)
+	rounds := flags.Int("rounds", 1000, "rounds is how many rounds of fictitious play to compute the strategy with")
+	out := flags.String("o", "strategy.json", "o is the file to write the strategy to")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: battleship [rule flags] nash [flags]")
+		fmt.Fprintln(flags.Output(), "Only small boards and fleets can be solved, i.e. --size=5 --fleet=small")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	strategy, err := ai.ComputeStrategy(rules, *rounds)
+	if err != nil {
+		return err
+	}
+	if err := strategy.Save(*out); err != nil {
+		return err
+	}
+	fmt.Printf("Computed a strategy over %v layouts\n", len(strategy.Layouts))
+	fmt.Printf("Placing by it holds out for %.2f shots on average against a greedy shooter (a heuristic upper bound), and shooting by it takes at most %.2f\n", strategy.PlacementValue, strategy.ShootingValue)
+	fmt.Printf("Wrote %v\n", *out)
+	return nil
+}
+
+// runTune runs the tune subcommand, given the arguments following it.
+// i.e. battleship tune -generations=20 -o ai-params.json
+func runTune(args []string, rules battleship.Rules) error {
+	flags := flag.NewFlagSet("tune", flag.ExitOnError)
+	population := flags.Int("population", 16, "population is how many sets of parameters are tried each generation")
+	generations := flags.Int("generations", 10, "generations is how many generations to evolve the parameters for")
+	games := flags.Int("games", 200, "games is how many games each set of parameters plays against the defaults, each generation")
+	out := flags.String("o", "ai-params.json", "o is the file to write the best parameters to")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: battleship [rule flags] tune [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	t := ai.Tuning{
+		Rules:       rules,
+		Population:  *population,
+		Generations: *generations,
+		Games:       *games,
+	}
+	best, err := t.Run(func(generation int, best ai.Candidate) {
+		fmt.Fprintf(os.Stderr, "Generation %v: %.1f%% wins with %v\n", generation, 100*best.WinRate, best.Params)
+	})
+	if err != nil {
+		return err
+	}
+	if err := best.Params.Save(*out); err != nil {
+		return err
+	}
+	fmt.Printf("Best parameters won %.1f%% of games against the defaults; %v\n", 100*best.WinRate, best.Params)
+	fmt.Printf("Wrote %v\n", *out)
+	return nil
+}
+
+// runBook runs the book command, given the arguments following it.
+// i.e. battleship --fleet=shapes book -o book.json
+func runBook(args []string, rules battleship.Rules) error {
+	flags := flag.NewFlagSet("book", flag.ExitOnError)
+	layouts := flags.Int("layouts", 20000, "layouts is how many fleet layouts to simulate")
+	shots := flags.Int("shots", 10, "shots is the most shots in the opening")
+	out := flags.String("o", "book.json", "o is the book to add the opening to; any opening for the same rules is replaced")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: battleship [rule flags] book [flags]")
+		fmt.Fprintln(flags.Output(), "Fleets are placed as AIs of the --difficulty flag place them")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	book, err := ai.LoadBook(*out)
+	if err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	opening, err := ai.GenerateOpening(rules, aiDifficulty, *layouts, *shots, rng)
+	if err != nil {
+		return err
+	}
+	book.Add(opening)
+	if err := book.Save(*out); err != nil {
+		return err
+	}
+
+	var found float64
+	for i, shot := range opening.Shots {
+		found += opening.Chances[i]
+		fmt.Printf("%2v. %-3v %4.1f%% to hit first, %4.1f%% found by now\n", i+1, shot, 100*opening.Chances[i], 100*found)
+	}
+	fmt.Printf("Wrote %v\n", *out)
+	return nil
+}
@@ -0,0 +1,325 @@
+package battleship
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Point is a position on the board, or an offset between two positions.
+type Point struct {
+	X, Y int
+}
+
+// ShipClass describes one kind of ship in a fleet.
+type ShipClass struct {
+	Name string
+
+	// Symbol is the character used to draw the ship on the board.
+	Symbol byte
+
+	// Cells are the positions the ship covers when placed facing up, as offsets from the position it is placed at.
+	// They must include 0,0 and be connected orthogonally.
+	// i.e. a straight ship of length 3 is 0,0 0,1 0,2
+	Cells []Point
+}
+
+// Line returns the cells of a straight ship of the given length.
+func Line(length int) []Point {
+	cells := make([]Point, length)
+	for i := range cells {
+		cells[i] = Point{0, i}
+	}
+	return cells
+}
+
+// Place returns the positions the ship covers when placed at x,y facing direction.
+// direction can be combined with Mirrored to reflect the ship before it is turned.
+func (c ShipClass) Place(x, y, direction int) ([]Point, error) {
+	mirror := direction&Mirrored > 0
+	direction &^= Mirrored
+
+	switch direction {
+	case UpLeft, UpRight, DownLeft, DownRight:
+		if !c.Straight() {
+			return nil, errors.New("only straight ships can be placed diagonally")
+		}
+	}
+
+	cells := make([]Point, len(c.Cells))
+	for i, cell := range c.Cells {
+		dx, dy := cell.X, cell.Y
+		if mirror {
+			dx = -dx
+		}
+
+		// straight ships are placed diagonally by stepping both ways for each position along them.
+		step := cell.X + cell.Y
+		switch direction {
+		case UpLeft:
+			dx, dy = -step, step
+		case UpRight:
+			dx, dy = step, step
+		case DownLeft:
+			dx, dy = -step, -step
+		case DownRight:
+			dx, dy = step, -step
+		case Up:
+		case Right:
+			dx, dy = dy, -dx
+		case Down:
+			dx, dy = -dx, -dy
+		case Left:
+			dx, dy = -dy, dx
+		default:
+			return nil, errors.New("invalid direction")
+		}
+
+		cells[i] = Point{x + dx, y + dy}
+	}
+	return cells, nil
+}
+
+// Straight returns true if the ship is a straight line.
+func (c ShipClass) Straight() bool {
+	vertical, horizontal := true, true
+	for _, cell := range c.Cells {
+		vertical = vertical && cell.X == 0
+		horizontal = horizontal && cell.Y == 0
+	}
+	return vertical || horizontal
+}
+
+// String draws the ship as it is when placed facing up, with its origin marked.
+func (c ShipClass) String() (str string) {
+	var minx, maxx, miny, maxy int
+	for _, cell := range c.Cells {
+		if cell.X < minx {
+			minx = cell.X
+		}
+		if cell.X > maxx {
+			maxx = cell.X
+		}
+		if cell.Y < miny {
+			miny = cell.Y
+		}
+		if cell.Y > maxy {
+			maxy = cell.Y
+		}
+	}
+
+	for y := maxy; y >= miny; y-- {
+		for x := minx; x <= maxx; x++ {
+			switch {
+			case x == 0 && y == 0:
+				str += "@ "
+			case c.has(x, y):
+				str += string(rune(c.Symbol)) + " "
+			default:
+				str += "  "
+			}
+		}
+		str = strings.TrimRight(str, " ") + "\n"
+	}
+	return
+}
+
+// Format returns the ship as a line of a fleet file, as read by ParseFleet.
+func (c ShipClass) Format() string {
+	cells := make([]string, len(c.Cells))
+	for i, cell := range c.Cells {
+		cells[i] = fmt.Sprintf("%v,%v", cell.X, cell.Y)
+	}
+	return fmt.Sprintf("%v %c %v", strings.Replace(c.Name, " ", "_", -1), c.Symbol, strings.Join(cells, " "))
+}
+
+// has returns true if the offset x,y is one of the ship's cells.
+func (c ShipClass) has(x, y int) bool {
+	for _, cell := range c.Cells {
+		if cell.X == x && cell.Y == y {
+			return true
+		}
+	}
+	return false
+}
+
+// Fleet is the set of ships each player places on their board.
+// The ship type of the ship at index i is (i+1) << 5, so a fleet can have at most 7 ships.
+type Fleet []ShipClass
+
+// MaxFleetSize is the most ships the ship bits of a board position can describe.
+const MaxFleetSize = shipMask >> 5
+
+// ClassicFleet is the fleet of the classic game,
+// with ship types matching the ship constants.
+var ClassicFleet = Fleet{
+	{Name: "Carrier", Symbol: 'C', Cells: Line(5)},
+	{Name: "Battleship", Symbol: 'B', Cells: Line(4)},
+	{Name: "Destroyer", Symbol: 'D', Cells: Line(3)},
+	{Name: "Submarine", Symbol: 'S', Cells: Line(3)},
+	{Name: "Patrol Boat", Symbol: 'P', Cells: Line(2)},
+}
+
+// ShapesFleet is a fleet of odd shaped ships.
+var ShapesFleet = Fleet{
+	{Name: "L-Ship", Symbol: 'L', Cells: []Point{{0, 0}, {0, 1}, {0, 2}, {1, 0}}},
+	{Name: "T-Ship", Symbol: 'T', Cells: []Point{{0, 0}, {1, 0}, {2, 0}, {1, 1}}},
+	{Name: "Z-Ship", Symbol: 'Z', Cells: []Point{{0, 0}, {1, 0}, {1, 1}, {2, 1}}},
+	{Name: "Battleship", Symbol: 'B', Cells: Line(4)},
+	{Name: "Destroyer", Symbol: 'D', Cells: Line(3)},
+	{Name: "Patrol Boat", Symbol: 'P', Cells: Line(2)},
+}
+
+// SmallFleet is a fleet for small boards, small enough for the whole game to be analysed.
+var SmallFleet = Fleet{
+	{Name: "Destroyer", Symbol: 'D', Cells: Line(3)},
+	{Name: "Patrol Boat", Symbol: 'P', Cells: Line(2)},
+}
+
+var fleetNames = map[string]Fleet{
+	"classic": ClassicFleet,
+	"shapes":  ShapesFleet,
+	"small":   SmallFleet,
+}
+
+// Ships returns the ship types of the fleet.
+func (f Fleet) Ships() []byte {
+	ships := make([]byte, len(f))
+	for i := range f {
+		ships[i] = byte(i+1) << 5
+	}
+	return ships
+}
+
+// Class returns the class of the given ship type.
+// If ok is false, the fleet has no such ship.
+func (f Fleet) Class(ship byte) (class ShipClass, ok bool) {
+	i := int(ship>>5) - 1
+	if ship&^shipMask != 0 || i < 0 || i >= len(f) {
+		return ShipClass{}, false
+	}
+	return f[i], true
+}
+
+// Name returns the name of the given ship type.
+func (f Fleet) Name(ship byte) string {
+	if class, ok := f.Class(ship); ok {
+		return class.Name
+	}
+	return "unknown ship"
+}
+
+// Straight returns true if every ship in the fleet is a straight line.
+func (f Fleet) Straight() bool {
+	for _, class := range f {
+		if !class.Straight() {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadFleet returns the named fleet, or if there is none by that name, reads a fleet from the file at the given path.
+func LoadFleet(name string) (Fleet, error) {
+	if fleet, ok := fleetNames[name]; ok {
+		return fleet, nil
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseFleet(file)
+}
+
+// ParseFleet reads a fleet definition, with one ship per line in the format
+//
+//	[name] [symbol] [cells...]
+//
+// where cells are x,y offsets, and underscores in the name are replaced with spaces.
+// Empty lines and lines starting with # are ignored.
+// i.e. "L-Ship L 0,0 0,1 0,2 1,0"
+func ParseFleet(r io.Reader) (Fleet, error) {
+	var fleet Fleet
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		str := strings.TrimSpace(scanner.Text())
+		if str == "" || str[0] == '#' {
+			continue
+		}
+
+		args := strings.Fields(str)
+		if len(args) < 3 || len(args[1]) != 1 {
+			return nil, fmt.Errorf("line %v: want [name] [symbol] [cells...]", lineNum)
+		}
+
+		class := ShipClass{
+			Name:   strings.Replace(args[0], "_", " ", -1),
+			Symbol: strings.ToUpper(args[1])[0],
+		}
+		for _, arg := range args[2:] {
+			xy := strings.Split(arg, ",")
+			if len(xy) != 2 {
+				return nil, fmt.Errorf("line %v: invalid cell %v", lineNum, arg)
+			}
+			x, errx := strconv.Atoi(xy[0])
+			y, erry := strconv.Atoi(xy[1])
+			if errx != nil || erry != nil {
+				return nil, fmt.Errorf("line %v: invalid cell %v", lineNum, arg)
+			}
+			class.Cells = append(class.Cells, Point{x, y})
+		}
+
+		if err := class.validate(); err != nil {
+			return nil, fmt.Errorf("line %v: %v", lineNum, err)
+		}
+		fleet = append(fleet, class)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(fleet) == 0 {
+		return nil, errors.New("fleet has no ships")
+	}
+	if len(fleet) > MaxFleetSize {
+		return nil, fmt.Errorf("fleet has %v ships, but can have at most %v", len(fleet), MaxFleetSize)
+	}
+	return fleet, nil
+}
+
+// validate checks the ship's cells include the origin, don't repeat, are connected, and fit on the board.
+func (c ShipClass) validate() error {
+	if !c.has(0, 0) {
+		return errors.New("cells must include 0,0")
+	}
+
+	// flood fill from the origin, every cell should be reached exactly once.
+	reached := map[Point]bool{{0, 0}: true}
+	stack := []Point{{0, 0}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, next := range []Point{{p.X + 1, p.Y}, {p.X - 1, p.Y}, {p.X, p.Y + 1}, {p.X, p.Y - 1}} {
+			if !reached[next] && c.has(next.X, next.Y) {
+				reached[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+
+	if len(reached) != len(c.Cells) {
+		return errors.New("cells must be connected, and not repeat")
+	}
+	for _, cell := range c.Cells {
+		if cell.X <= -BoardSize || cell.X >= BoardSize || cell.Y <= -BoardSize || cell.Y >= BoardSize {
+			return errors.New("ship is larger than the board")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package battleship
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShipClassPlace(t *testing.T) {
+	lShip := ShipClass{Name: "L-Ship", Symbol: 'L', Cells: []Point{{0, 0}, {0, 1}, {0, 2}, {1, 0}}}
+
+	testCases := []struct {
+		desc      string
+		direction int
+		want      []Point
+	}{
+		{
+			desc:      "up",
+			direction: Up,
+			want:      []Point{{4, 4}, {4, 5}, {4, 6}, {5, 4}},
+		},
+		{
+			desc:      "right",
+			direction: Right,
+			want:      []Point{{4, 4}, {5, 4}, {6, 4}, {4, 3}},
+		},
+		{
+			desc:      "down",
+			direction: Down,
+			want:      []Point{{4, 4}, {4, 3}, {4, 2}, {3, 4}},
+		},
+		{
+			desc:      "left",
+			direction: Left,
+			want:      []Point{{4, 4}, {3, 4}, {2, 4}, {4, 5}},
+		},
+		{
+			desc:      "up mirrored",
+			direction: Up | Mirrored,
+			want:      []Point{{4, 4}, {4, 5}, {4, 6}, {3, 4}},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			cells, err := lShip.Place(4, 4, tC.direction)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range tC.want {
+				if cells[i] != tC.want[i] {
+					t.Fatalf("wanted %v, got %v", tC.want, cells)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFleet(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		input   string
+		want    Fleet
+		wanterr bool
+	}{
+		{
+			desc:  "shapes",
+			input: "# comment\nL-Ship L 0,0 0,1 0,2 1,0\n\nPatrol_Boat p 0,0 0,1\n",
+			want: Fleet{
+				{Name: "L-Ship", Symbol: 'L', Cells: []Point{{0, 0}, {0, 1}, {0, 2}, {1, 0}}},
+				{Name: "Patrol Boat", Symbol: 'P', Cells: []Point{{0, 0}, {0, 1}}},
+			},
+		},
+		{
+			desc:    "no origin",
+			input:   "Ship S 0,1 0,2",
+			wanterr: true,
+		},
+		{
+			desc:    "not connected",
+			input:   "Ship S 0,0 1,1",
+			wanterr: true,
+		},
+		{
+			desc:    "repeated cell",
+			input:   "Ship S 0,0 0,1 0,1",
+			wanterr: true,
+		},
+		{
+			desc:    "bad cell",
+			input:   "Ship S 0,0 0;1",
+			wanterr: true,
+		},
+		{
+			desc:    "too many ships",
+			input:   strings.Repeat("Ship S 0,0\n", MaxFleetSize+1),
+			wanterr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			fleet, err := ParseFleet(strings.NewReader(tC.input))
+			if tC.wanterr {
+				if err == nil {
+					t.Fatalf("wanted error but didn't get one, got %v", fleet)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if len(fleet) != len(tC.want) {
+				t.Fatalf("wanted %v, got %v", tC.want, fleet)
+			}
+			for i := range fleet {
+				if fleet[i].Name != tC.want[i].Name || fleet[i].Symbol != tC.want[i].Symbol || len(fleet[i].Cells) != len(tC.want[i].Cells) {
+					t.Fatalf("wanted %v, got %v", tC.want, fleet)
+				}
+			}
+		})
+	}
+}
+
+func TestIsSunkShapes(t *testing.T) {
+	rules := Rules{Fleet: ShapesFleet}
+	var b Board
+	tShip := rules.GetFleet().Ships()[1]
+	if err := rules.PlaceShip(&b, 3, 3, Left, tShip); err != nil {
+		t.Fatal(err)
+	}
+
+	cells, _ := ShapesFleet[1].Place(3, 3, Left)
+	for i, cell := range cells {
+		hit, sunk := b.OpponentShot(cell.X, cell.Y)
+		if !hit {
+			t.Fatalf("missed ship at %v\n%v", FormatPosition(cell.X, cell.Y), b.Format(ShapesFleet))
+		}
+		if last := i == len(cells)-1; last != (sunk == tShip) {
+			t.Fatalf("wanted sunk only on the last shot, got %v on shot %v", sunk, i)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package battleship
+
+// GameResult is the outcome of a game played with PlayGame.
+type GameResult struct {
+	// Winner is the number of the player that won; 1 or 2.
+	Winner int
+	// Moves holds the attacks each player made, in order.
+	Moves [2][]Move
+}
+
+// Move is an attack made during a game, and what came of it.
+type Move struct {
+	Attack Attack
+	// Shots holds the shots that landed.
+	Shots []Shot
+	// Contact is the result of a sonar ping.
+	Contact bool
+}
+
+// PlayGame plays a game between p1 and p2, with p1 going first, until one of them wins.
+// turn, if not nil, is called before each turn with the number of the player taking it.
+// If a player returns an error they forfeit, and the error is returned along with the other player as the winner.
+// The players aren't told the game is over, see GameOver.
+func PlayGame(p1, p2 Player, turn func(player int)) (GameResult, error) {
+	var result GameResult
+	players := [2]Player{p1, p2}
+	links := [2]*recordingLink{
+		{Link: NewLocalLink(p2), moves: &result.Moves[0]},
+		{Link: NewLocalLink(p1), moves: &result.Moves[1]},
+	}
+
+	for i := 0; ; i = 1 - i {
+		if turn != nil {
+			turn(i + 1)
+		}
+		won, err := players[i].Turn(links[i])
+		if err != nil {
+			result.Winner = 2 - i
+			return result, err
+		}
+		if won {
+			result.Winner = i + 1
+			return result, nil
+		}
+	}
+}
+
+// recordingLink is a Link that records the moves made through it.
+type recordingLink struct {
+	Link
+	moves *[]Move
+}
+
+// TakeShot implements Link
+func (rl *recordingLink) TakeShot(x, y int) (bool, byte) {
+	hit, sunk := rl.Link.TakeShot(x, y)
+	*rl.moves = append(*rl.moves, Move{
+		Attack: Attack{Weapon: WeaponShot, X: x, Y: y},
+		Shots:  []Shot{{X: x, Y: y, Hit: hit, Sunk: sunk}},
+	})
+	return hit, sunk
+}
+
+// Sonar implements Link
+func (rl *recordingLink) Sonar(x, y int) bool {
+	contact := rl.Link.Sonar(x, y)
+	*rl.moves = append(*rl.moves, Move{
+		Attack:  Attack{Weapon: WeaponSonar, X: x, Y: y},
+		Contact: contact,
+	})
+	return contact
+}
+
+// Airstrike implements Link
+func (rl *recordingLink) Airstrike(x, y int) []Shot {
+	shots := rl.Link.Airstrike(x, y)
+	*rl.moves = append(*rl.moves, Move{
+		Attack: Attack{Weapon: WeaponAirstrike, X: x, Y: y},
+		Shots:  shots,
+	})
+	return shots
+}
+
+// Torpedo implements Link
+func (rl *recordingLink) Torpedo(y int, fromRight bool) (Shot, bool) {
+	shot, ok := rl.Link.Torpedo(y, fromRight)
+	move := Move{Attack: Attack{Weapon: WeaponTorpedo, Y: y, FromRight: fromRight}}
+	if ok {
+		move.Shots = []Shot{shot}
+	}
+	*rl.moves = append(*rl.moves, move)
+	return shot, ok
+}
+
+// replayLink is a Link that gives the results of recorded moves, in order, so they can be launched again.
+type replayLink struct {
+	moves []Move
+}
+
+// NewReplayLink returns a Link that gives the results of the given moves, in order.
+// Launching the moves again, in the same order, replays them.
+func NewReplayLink(moves []Move) Link {
+	return &replayLink{moves: moves}
+}
+
+// next returns the next recorded move.
+func (rl *replayLink) next() Move {
+	move := rl.moves[0]
+	rl.moves = rl.moves[1:]
+	return move
+}
+
+// TakeShot implements Link
+func (rl *replayLink) TakeShot(x, y int) (bool, byte) {
+	shot := rl.next().Shots[0]
+	return shot.Hit, shot.Sunk
+}
+
+// Sonar implements Link
+func (rl *replayLink) Sonar(x, y int) bool {
+	return rl.next().Contact
+}
+
+// Airstrike implements Link
+func (rl *replayLink) Airstrike(x, y int) []Shot {
+	return rl.next().Shots
+}
+
+// Torpedo implements Link
+func (rl *replayLink) Torpedo(y int, fromRight bool) (Shot, bool) {
+	move := rl.next()
+	if len(move.Shots) == 0 {
+		return Shot{}, false
+	}
+	return move.Shots[0], true
+}
@@ -0,0 +1,175 @@
+package battleship
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultHistoryPath returns where opponent history is kept if no other path is given.
+func DefaultHistoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "battleship", "history.json")
+}
+
+// LoadHistory reads the history file at path.
+// If there is no file yet, an empty history is returned, to be created when saved.
+func LoadHistory(path string) (*History, error) {
+	h := &History{
+		path:      path,
+		Opponents: make(map[string]*OpponentHistory),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, err
+	}
+	if h.Opponents == nil {
+		h.Opponents = make(map[string]*OpponentHistory)
+	}
+	return h, nil
+}
+
+// History holds what has been learnt about opponents over many games, keyed by opponent name and the rules played by.
+type History struct {
+	path      string
+	Opponents map[string]*OpponentHistory `json:"opponents"`
+}
+
+// OpponentHistory holds the fleet layouts an opponent has used, and the shots they have taken, in past games.
+// Each layout or game's shots is a list of positions, formatted with FormatPosition.
+type OpponentHistory struct {
+	Layouts [][]string `json:"layouts"`
+	Shots   [][]string `json:"shots"`
+}
+
+// Opponent returns the history of the named opponent in games by the given rules, creating it if there is none.
+func (h *History) Opponent(name string, rules Rules) *OpponentHistory {
+	key := historyKey(name, rules)
+	oh, ok := h.Opponents[key]
+	if !ok {
+		oh = &OpponentHistory{}
+		h.Opponents[key] = oh
+	}
+	return oh
+}
+
+// historyKey returns the key the named opponent's history is kept under, for games by the given rules.
+// Layouts on other board sizes, or of other fleets, say little about where they'll place ships, so they're kept apart.
+func historyKey(name string, rules Rules) string {
+	fleet := rules.GetFleet()
+	ships := make([]string, len(fleet))
+	for i, class := range fleet {
+		ships[i] = class.Format()
+	}
+	size := rules.GetSize()
+	return fmt.Sprintf("%v %vx%v %v", name, size, size, strings.Join(ships, ", "))
+}
+
+// Save writes the history back to the file it was loaded from.
+func (h *History) Save() error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(h.path, data, 0644)
+}
+
+// AddLayout records the positions of the ships on the opponent's board.
+func (oh *OpponentHistory) AddLayout(b *Board) {
+	var layout []string
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
+			if b[x][y]&shipMask > 0 {
+				layout = append(layout, FormatPosition(x, y))
+			}
+		}
+	}
+	oh.Layouts = append(oh.Layouts, layout)
+}
+
+// AddShots records the positions the opponent shot on the given board, being the board of the player they played against.
+func (oh *OpponentHistory) AddShots(b *Board) {
+	var shots []string
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
+			if b[x][y]&opponentHit > 0 {
+				shots = append(shots, FormatPosition(x, y))
+			}
+		}
+	}
+	oh.Shots = append(oh.Shots, shots)
+}
+
+// ShotHeatmap returns the fraction of past games the opponent shot each position in.
+func (oh *OpponentHistory) ShotHeatmap() (heat [BoardSize][BoardSize]float64) {
+	for _, shots := range oh.Shots {
+		for _, position := range shots {
+			x, y, err := ParsePosition(position)
+			if err != nil {
+				continue
+			}
+			heat[x][y] += 1 / float64(len(oh.Shots))
+		}
+	}
+	return
+}
+
+// priorStrength is how many games worth of evenly spread ships the prior starts with,
+// so a few games don't make the AI too sure of itself.
+const priorStrength = 3
+
+// Prior returns how much more likely than average the opponent is to have a ship at each position,
+// learnt from their past layouts. With no history, every position is 1.
+func (oh *OpponentHistory) Prior() (prior [BoardSize][BoardSize]float64) {
+	var counts [BoardSize][BoardSize]float64
+	var total float64
+	for _, layout := range oh.Layouts {
+		for _, position := range layout {
+			x, y, err := ParsePosition(position)
+			if err != nil {
+				continue
+			}
+			counts[x][y]++
+			total++
+		}
+	}
+
+	if total == 0 {
+		// nothing learnt yet
+		for x := 0; x < BoardSize; x++ {
+			for y := 0; y < BoardSize; y++ {
+				prior[x][y] = 1
+			}
+		}
+		return
+	}
+
+	games := float64(len(oh.Layouts))
+	average := total / games / (BoardSize * BoardSize)
+
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
+			// blend the observed rate with the average, as if priorStrength games had ships spread evenly.
+			rate := (counts[x][y] + priorStrength*average) / (games + priorStrength)
+			prior[x][y] = rate / average
+		}
+	}
+	return
+}
@@ -0,0 +1,61 @@
+package battleship
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "battleship")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history", "history.json")
+
+	history, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("loading missing history: %v", err)
+	}
+
+	// an opponent that always puts their patrol boat at a1.
+	var b Board
+	if err := b.PlaceShip(0, 0, Up, ShipPatrolBoat); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		history.Opponent("predictable", Rules{}).AddLayout(&b)
+	}
+	if err := history.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err = LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(history.Opponent("predictable", Rules{}).Layouts); n != 10 {
+		t.Fatalf("wanted 10 layouts after loading, got %v", n)
+	}
+
+	prior := history.Opponent("predictable", Rules{}).Prior()
+	if prior[0][0] <= 1 || prior[0][1] <= 1 {
+		t.Fatalf("wanted the usual patrol boat positions to be more likely than average, got %v and %v", prior[0][0], prior[0][1])
+	}
+	if prior[5][5] >= 1 {
+		t.Fatalf("wanted an unused position to be less likely than average, got %v", prior[5][5])
+	}
+
+	for _, rules := range []Rules{{Size: 5}, {Fleet: SmallFleet}} {
+		if n := len(history.Opponent("predictable", rules).Layouts); n != 0 {
+			t.Fatalf("wanted no layouts from games by other rules, got %v", n)
+		}
+	}
+
+	prior = history.Opponent("stranger", Rules{}).Prior()
+	if prior[0][0] != 1 {
+		t.Fatalf("wanted no preference for a new opponent, got %v", prior[0][0])
+	}
+}
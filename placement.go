@@ -0,0 +1,93 @@
+package battleship
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Constants for AI difficulty, deciding how carefully AIs place their ships.
+const (
+	DifficultyEasy   = iota // ships placed uniformly at random.
+	DifficultyNormal        // ships placed where an opponent hunting by placement density looks last.
+	DifficultyHard          // ships placed where the opponent has shot least in past games, if known, and endgames solved exactly.
+)
+
+var difficultyNames = map[int]string{
+	DifficultyEasy:   "easy",
+	DifficultyNormal: "normal",
+	DifficultyHard:   "hard",
+}
+
+// ParseDifficulty parses the name of a difficulty, as used on the command line.
+// i.e. "hard" = DifficultyHard
+func ParseDifficulty(name string) (int, error) {
+	for difficulty, difficultyName := range difficultyNames {
+		if name == difficultyName {
+			return difficulty, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown difficulty %v", name)
+}
+
+// placementCandidates is how many random boards are compared when looking for a hard to find layout.
+// Comparing more finds better layouts, but the best layouts are also the most predictable.
+const placementCandidates = 30
+
+// PlacementBoard creates a board with ships placed by the strategy for the given difficulty.
+// opponent is the history of the opponent being played, and may be nil if they aren't known.
+func PlacementBoard(rng *rand.Rand, rules Rules, difficulty int, opponent *OpponentHistory) Board {
+	switch {
+	case difficulty == DifficultyHard && opponent != nil && len(opponent.Shots) > 0:
+		return CounterBoard(rng, rules, opponent.ShotHeatmap())
+	case difficulty >= DifficultyNormal:
+		return DensityBoard(rng, rules)
+	default:
+		return UniformBoard(rng, rules)
+	}
+}
+
+// Density returns how many ways the fleet's ships can cover each position on an empty board.
+// An opponent with nothing else to go on is most likely to hit ships on the positions with the highest density.
+func (r Rules) Density() (density [BoardSize][BoardSize]float64) {
+	for _, ship := range r.GetFleet().Ships() {
+		for _, cells := range r.Placements(ship) {
+			for _, cell := range cells {
+				density[cell.X][cell.Y]++
+			}
+		}
+	}
+	return
+}
+
+// DensityBoard creates a board with ships placed to minimise the chance of being hit by an opponent that hunts by placement density.
+func DensityBoard(rng *rand.Rand, rules Rules) Board {
+	return leastExposedBoard(rng, rules, rules.Density())
+}
+
+// CounterBoard creates a board with ships placed away from the positions an opponent has shot most often, as given in shots.
+func CounterBoard(rng *rand.Rand, rules Rules, shots [BoardSize][BoardSize]float64) Board {
+	return leastExposedBoard(rng, rules, shots)
+}
+
+// leastExposedBoard creates random boards, and returns the one with the lowest total exposure over the positions its ships cover.
+func leastExposedBoard(rng *rand.Rand, rules Rules, exposure [BoardSize][BoardSize]float64) (best Board) {
+	generator := NewUniformGenerator(rules)
+	bestScore := -1.0
+	for i := 0; i < placementCandidates; i++ {
+		b := generator.Board(rng)
+
+		var score float64
+		for x := 0; x < BoardSize; x++ {
+			for y := 0; y < BoardSize; y++ {
+				if b[x][y]&shipMask > 0 {
+					score += exposure[x][y]
+				}
+			}
+		}
+
+		if bestScore < 0 || score < bestScore {
+			best, bestScore = b, score
+		}
+	}
+	return
+}
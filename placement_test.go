@@ -0,0 +1,52 @@
+package battleship
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// exposure sums the given heatmap over the positions covered by ships.
+func exposure(b Board, heat [BoardSize][BoardSize]float64) (total float64) {
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
+			if b[x][y]&shipMask > 0 {
+				total += heat[x][y]
+			}
+		}
+	}
+	return
+}
+
+func TestPlacementBoard(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	rules := Rules{}
+	density := rules.Density()
+
+	// an opponent that always shoots the left half of the board first.
+	var opponent OpponentHistory
+	var shot Board
+	for x := 0; x < BoardSize/2; x++ {
+		for y := 0; y < BoardSize; y++ {
+			shot.OpponentShot(x, y)
+		}
+	}
+	opponent.AddShots(&shot)
+	shots := opponent.ShotHeatmap()
+
+	var random, normal, randomShots, hardShots float64
+	for i := 0; i < 100; i++ {
+		b := PlacementBoard(rng, rules, DifficultyEasy, &opponent)
+		random += exposure(b, density)
+		randomShots += exposure(b, shots)
+
+		normal += exposure(PlacementBoard(rng, rules, DifficultyNormal, &opponent), density)
+		hardShots += exposure(PlacementBoard(rng, rules, DifficultyHard, &opponent), shots)
+	}
+
+	if normal >= random {
+		t.Fatalf("wanted normal boards less exposed to density hunting than random boards, got %v and %v", normal, random)
+	}
+	if hardShots >= randomShots {
+		t.Fatalf("wanted hard boards less exposed to the opponent's shots than random boards, got %v and %v", hardShots, randomShots)
+	}
+}
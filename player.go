@@ -1,4 +1,4 @@
-package main
+package battleship
 
 // Player is an interface to a single player's game session.
 // It allows any kind of player (human game interfaces, AIs) to be generalised into a single interface.
@@ -9,6 +9,40 @@ type Player interface {
 
 	// Turn takes a turn in the game, returning true if the player won during the turn.
 	Turn(Link) (won bool, err error)
+
+	// IncomingFire is called as each of the opponent's attacks lands on the player's board, during the opponent's turn.
+	IncomingFire(fire IncomingFire)
+}
+
+// GameEnder is implemented by Players that want to know when the game is over.
+type GameEnder interface {
+	// GameOver is called once the game is over, with the opponent's board, revealing where their ships were.
+	GameOver(won bool, opponent *Board) error
+}
+
+// IncomingFire is a shot the opponent has landed on a player's board, or a sonar ping they've made of it.
+type IncomingFire struct {
+	Shot
+	// Ship is the player's ship that was hit, or 0 for a miss.
+	Ship byte
+	// Weapon is the weapon the opponent attacked with.
+	// For a sonar ping, X,Y is the centre of the area pinged, Hit is true if it found a ship, and Ship is 0.
+	Weapon int
+}
+
+// GameOver tells the winner and loser, if they implement GameEnder, that the game is over.
+func GameOver(winner, loser Player) error {
+	if e, ok := winner.(GameEnder); ok {
+		if err := e.GameOver(true, loser.GetBoard()); err != nil {
+			return err
+		}
+	}
+	if e, ok := loser.(GameEnder); ok {
+		if err := e.GameOver(false, winner.GetBoard()); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Link is an interface, used by a Player, for querying information about the other Player.
@@ -18,9 +52,29 @@ type Link interface {
 	// TakeShot is called when a player takes a shot at the other player, returning if it was a hit or not, and if sunk != 0, she ship that was sunk.
 	// Players should keep track of their own score.
 	TakeShot(x, y int) (hit bool, sunk byte)
+
+	// Sonar pings the area around x,y, returning true if any ship occupies it.
+	// Players should keep track of their own special weapon uses.
+	Sonar(x, y int) (contact bool)
+
+	// Airstrike fires at the row segment centred on x,y, returning the shots that landed on positions not shot before.
+	Airstrike(x, y int) []Shot
+
+	// Torpedo fires a torpedo along row y from the left edge, or the right if fromRight is true,
+	// returning the shot where it exploded. If ok is false the torpedo didn't hit anything.
+	Torpedo(y int, fromRight bool) (shot Shot, ok bool)
+}
+
+// Shot is the result of a shot at a single position.
+type Shot struct {
+	X, Y int
+	Hit  bool
+	// Sunk is the ship sunk by the shot, or 0 if nothing was sunk.
+	Sunk byte
 }
 
 // NewLocalLink returns a Link for communicating with the Player p.
+// p is told of every attack that lands on its board.
 func NewLocalLink(p Player) Link {
 	return &localLink{
 		p: p,
@@ -33,5 +87,41 @@ type localLink struct {
 
 // TakeShot implements Link
 func (ll *localLink) TakeShot(x, y int) (bool, byte) {
-	return ll.p.GetBoard().OpponentShot(x, y)
+	hit, sunk := ll.p.GetBoard().OpponentShot(x, y)
+	ll.notify(WeaponShot, Shot{X: x, Y: y, Hit: hit, Sunk: sunk})
+	return hit, sunk
+}
+
+// Sonar implements Link
+func (ll *localLink) Sonar(x, y int) bool {
+	contact := ll.p.GetBoard().OpponentSonar(x, y)
+	ll.p.IncomingFire(IncomingFire{Shot: Shot{X: x, Y: y, Hit: contact}, Weapon: WeaponSonar})
+	return contact
+}
+
+// Airstrike implements Link
+func (ll *localLink) Airstrike(x, y int) []Shot {
+	shots := ll.p.GetBoard().OpponentAirstrike(x, y)
+	ll.notify(WeaponAirstrike, shots...)
+	return shots
+}
+
+// Torpedo implements Link
+func (ll *localLink) Torpedo(y int, fromRight bool) (Shot, bool) {
+	shot, ok := ll.p.GetBoard().OpponentTorpedo(y, fromRight)
+	if ok {
+		ll.notify(WeaponTorpedo, shot)
+	}
+	return shot, ok
+}
+
+// notify tells the player of shots from weapon that have landed on its board.
+func (ll *localLink) notify(weapon int, shots ...Shot) {
+	for _, shot := range shots {
+		fire := IncomingFire{Shot: shot, Weapon: weapon}
+		if shot.Hit {
+			fire.Ship = ll.p.GetBoard().ShipAt(shot.X, shot.Y)
+		}
+		ll.p.IncomingFire(fire)
+	}
 }
@@ -0,0 +1,299 @@
+package battleship
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Constants for the bonus shot rule,
+// deciding when a player gets to shoot again.
+const (
+	BonusNone   = iota // never shoot again; the classic game.
+	BonusOnHit         // shoot again after any hit.
+	BonusOnSink        // shoot again only after sinking a ship.
+)
+
+// BonusNames maps the bonus shot rules to their names, as used on the command line.
+var BonusNames = map[int]string{
+	BonusNone:   "none",
+	BonusOnHit:  "hit",
+	BonusOnSink: "sink",
+}
+
+// ParseBonusShot parses the name of a bonus shot rule, as used on the command line.
+// i.e. "hit" = BonusOnHit
+func ParseBonusShot(name string) (int, error) {
+	for bonus, bonusName := range BonusNames {
+		if name == bonusName {
+			return bonus, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown bonus shot rule %v", name)
+}
+
+// Rules holds the optional rules a game is played with.
+// The zero value is the classic game.
+type Rules struct {
+	// NoTouching forbids ships from being placed next to each other, orthogonally or diagonally.
+	NoTouching bool
+
+	// BonusShot decides when a player gets another shot in the same turn.
+	BonusShot int
+
+	// Arsenal is the special weapons each player starts with.
+	// An empty arsenal is the classic game.
+	Arsenal Arsenal
+
+	// Diagonal allows straight ships to be placed diagonally.
+	Diagonal bool
+
+	// Fleet is the ships each player places.
+	// If nil, the classic fleet is used.
+	Fleet Fleet
+
+	// Size limits the game to the bottom left Size x Size positions of the board.
+	// Zero is the full board.
+	Size int
+}
+
+// GetSize returns the width and height of the part of the board being played on.
+func (r Rules) GetSize() int {
+	if r.Size <= 0 || r.Size > BoardSize {
+		return BoardSize
+	}
+	return r.Size
+}
+
+// InBounds returns true if x,y is on the part of the board being played on.
+func (r Rules) InBounds(x, y int) bool {
+	return x >= 0 && x < r.GetSize() && y >= 0 && y < r.GetSize()
+}
+
+// NewBoard returns an empty board for the game.
+// Positions outside the part of the board being played on are marked as water, so players know not to shoot them.
+func (r Rules) NewBoard() (b Board) {
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
+			if !r.InBounds(x, y) {
+				b.PlayerMarkWater(x, y)
+			}
+		}
+	}
+	return
+}
+
+// Check returns an error if the rules can't be played by.
+func (r Rules) Check() error {
+	if r.Size < 0 || r.Size > BoardSize {
+		return fmt.Errorf("size must be between 1 and %v", BoardSize)
+	}
+	if len(r.GetFleet()) > MaxFleetSize {
+		return fmt.Errorf("fleet can have at most %v ships", MaxFleetSize)
+	}
+	for _, ship := range r.GetFleet().Ships() {
+		if len(r.Placements(ship)) == 0 {
+			return fmt.Errorf("%v doesn't fit on the board", r.GetFleet().Name(ship))
+		}
+	}
+	if !r.fleetFits() {
+		return errors.New("the fleet doesn't fit on the board")
+	}
+	return nil
+}
+
+// fleetFits returns true if the whole fleet can be placed on the board at once.
+// It searches every combination of placements, stopping at the first layout found,
+// so fleets that only fit a few ways are found to fit.
+func (r Rules) fleetFits() bool {
+	type ship struct {
+		// masks and avoid hold the positions each placement covers, and can't have other ships on.
+		masks, avoid []Bitboard
+		size         int
+	}
+	var ships []ship
+	var area Bitboard
+	for _, shipType := range r.GetFleet().Ships() {
+		var s ship
+		for _, cells := range r.Placements(shipType) {
+			mask := BitboardOf(cells)
+			s.masks = append(s.masks, mask)
+			if r.NoTouching {
+				s.avoid = append(s.avoid, mask.Halo())
+			} else {
+				s.avoid = append(s.avoid, mask)
+			}
+			s.size = len(cells)
+			area = area.Or(mask)
+		}
+		ships = append(ships, s)
+	}
+	// the largest ships have the fewest places to go, so are placed first.
+	sort.SliceStable(ships, func(i, j int) bool { return ships[i].size > ships[j].size })
+
+	// left holds the number of positions the ships from each index on cover.
+	left := make([]int, len(ships)+1)
+	for i := len(ships) - 1; i >= 0; i-- {
+		left[i] = left[i+1] + ships[i].size
+	}
+
+	var place func(i, from int, occupied, blocked Bitboard) bool
+	place = func(i, from int, occupied, blocked Bitboard) bool {
+		if i == len(ships) {
+			return true
+		}
+		if area.AndNot(blocked).Count() < left[i] {
+			// not enough room left for the remaining ships.
+			return false
+		}
+		for j := from; j < len(ships[i].masks); j++ {
+			if !ships[i].avoid[j].And(occupied).Empty() {
+				continue
+			}
+			// identical ships are placed in order of their placements, so each set of positions is only tried once.
+			next := 0
+			if i+1 < len(ships) && sameMasks(ships[i].masks, ships[i+1].masks) {
+				next = j + 1
+			}
+			covered := ships[i].masks[j]
+			if r.NoTouching {
+				covered = covered.Halo()
+			}
+			if place(i+1, next, occupied.Or(ships[i].masks[j]), blocked.Or(covered)) {
+				return true
+			}
+		}
+		return false
+	}
+	return place(0, 0, Bitboard{}, Bitboard{})
+}
+
+// sameMasks returns true if both ships have the same placements.
+func sameMasks(a, b []Bitboard) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mustCheck panics if the rules can't be played by.
+// It's for when boards have been tried for a long time without the fleet fitting, so it's never going to.
+func mustCheck(r Rules) {
+	if err := r.Check(); err != nil {
+		panic("battleship: " + err.Error())
+	}
+}
+
+// GetFleet returns the fleet being played with.
+func (r Rules) GetFleet() Fleet {
+	if r.Fleet == nil {
+		return ClassicFleet
+	}
+	return r.Fleet
+}
+
+// Won returns true if a player that has sunk score ships has won.
+func (r Rules) Won(score int) bool {
+	return score >= len(r.GetFleet())
+}
+
+// ShootAgain returns true if a shot with the given result grants the player another shot.
+func (r Rules) ShootAgain(hit bool, sunk byte) bool {
+	switch r.BonusShot {
+	case BonusOnHit:
+		return hit
+	case BonusOnSink:
+		return sunk != 0
+	default:
+		return false
+	}
+}
+
+// PlaceShip places a ship from the fleet at x,y facing direction, enforcing the placement rules.
+// If err is non-nil, the board will have not been modified.
+func (r Rules) PlaceShip(b *Board, x, y, direction int, shipType byte) error {
+	class, ok := r.GetFleet().Class(shipType)
+	if !ok {
+		return errors.New("invalid ship type")
+	}
+
+	switch direction &^ Mirrored {
+	case UpLeft, UpRight, DownLeft, DownRight:
+		if !r.Diagonal {
+			return errors.New("ships can't be placed diagonally")
+		}
+	}
+
+	cells, err := class.Place(x, y, direction)
+	if err != nil {
+		return err
+	}
+	for _, cell := range cells {
+		if !r.InBounds(cell.X, cell.Y) {
+			return errors.New("ship is off the board")
+		}
+	}
+
+	if r.NoTouching {
+		for _, cell := range cells {
+			if IsValid(cell.X, cell.Y) && b.HasShipNear(cell.X, cell.Y) {
+				return fmt.Errorf("ship would be touching another ship at %v", FormatPosition(cell.X, cell.Y))
+			}
+		}
+	}
+
+	return b.PlaceCells(cells, shipType)
+}
+
+// Placements returns every set of positions the given ship can cover on an empty board, as allowed by the rules.
+func (r Rules) Placements(shipType byte) [][]Point {
+	class, ok := r.GetFleet().Class(shipType)
+	if !ok {
+		return nil
+	}
+
+	directions := []int{Up, Down, Left, Right}
+	if r.Diagonal && class.Straight() {
+		directions = append(directions, UpLeft, UpRight, DownLeft, DownRight)
+	}
+	mirrors := []int{0}
+	if !class.Straight() {
+		mirrors = append(mirrors, Mirrored)
+	}
+
+	var placements [][]Point
+	seen := make(map[[2]uint64]bool)
+	for x := 0; x < r.GetSize(); x++ {
+		for y := 0; y < r.GetSize(); y++ {
+			for _, direction := range directions {
+			mirrors:
+				for _, mirror := range mirrors {
+					cells, err := class.Place(x, y, direction|mirror)
+					if err != nil {
+						continue
+					}
+
+					// different placements can cover the same positions, i.e. a patrol boat placed up from a1, or down from b1.
+					var key [2]uint64
+					for _, cell := range cells {
+						if !r.InBounds(cell.X, cell.Y) {
+							continue mirrors
+						}
+						i := cell.X*BoardSize + cell.Y
+						key[i/64] |= 1 << uint(i%64)
+					}
+					if !seen[key] {
+						seen[key] = true
+						placements = append(placements, cells)
+					}
+				}
+			}
+		}
+	}
+	return placements
+}
@@ -0,0 +1,279 @@
+// Package server hosts games of battleship over TCP, for clients as simple as netcat or telnet.
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stewi1014/battleship"
+	"github.com/stewi1014/battleship/terminal"
+)
+
+// waitPoll is how often a client waiting for an opponent checks if one has turned up.
+const waitPoll = 500 * time.Millisecond
+
+// DefaultTurnTimeout is how long clients have to take each turn, if the server doesn't set it.
+const DefaultTurnTimeout = 5 * time.Minute
+
+// hintLimit is how many hints a client can have each game, so they can't keep the server busy working them out.
+const hintLimit = 3
+
+// Server hosts games over TCP. Each client gets a line based session, played with a TerminalUI,
+// in which they place their ships and play an AI, or another client waiting for an opponent.
+type Server struct {
+	// Rules are the rules every game is played by.
+	Rules battleship.Rules
+	// NewAI creates the AI clients play against.
+	NewAI func(rules battleship.Rules) (battleship.Player, error)
+	// Log, if not nil, is written when clients come and go.
+	Log io.Writer
+	// TurnTimeout is how long a client has to take each turn before they forfeit the game, so they can't hold up
+	// their opponent forever. It's also how long they have to choose who to play, and to place their ships,
+	// before they're disconnected. If it is 0, DefaultTurnTimeout is used.
+	TurnTimeout time.Duration
+
+	// waiting is the client waiting for another to play against, if any.
+	mu      sync.Mutex
+	waiting *session
+}
+
+// session is a client's connection to the server.
+type session struct {
+	conn  net.Conn
+	input *bufio.Reader
+	tui   *terminal.TerminalUI
+
+	// matched is closed once a waiting client has an opponent, and stopped once it has stopped reading its input,
+	// so the opponent's goroutine can play the game.
+	// played is closed once the game is over.
+	matched, stopped, played chan struct{}
+}
+
+// ListenAndServe listens on the TCP address addr, and serves clients connecting to it.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return s.Serve(l)
+}
+
+// Serve serves clients connecting to l, each in its own goroutine, until l is closed.
+// It returns an error straight away if the rules can't be played by.
+func (s *Server) Serve(l net.Listener) error {
+	if err := s.Rules.Check(); err != nil {
+		return err
+	}
+	s.logf("serving on %v", l.Addr())
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// logf writes a line to Log, if it's set.
+func (s *Server) logf(format string, a ...interface{}) {
+	if s.Log != nil {
+		fmt.Fprintf(s.Log, format+"\n", a...)
+	}
+}
+
+// handle runs a client's session, playing games until they quit or disconnect.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	s.logf("%v connected", conn.RemoteAddr())
+
+	sess := &session{
+		conn:  conn,
+		input: bufio.NewReader(conn),
+	}
+	for {
+		err := s.play(sess)
+		if err == errQuit {
+			s.logf("%v quit", conn.RemoteAddr())
+			return
+		}
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			fmt.Fprintln(conn, "You took too long to reply; goodbye.")
+		}
+		if err != nil {
+			s.logf("%v disconnected; %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// errQuit is returned by play when the client asks to quit.
+var errQuit = errors.New("quit")
+
+// play asks the client who they want to play, has them place their ships, and plays a game.
+// The client has the turn timeout to choose, and again to place their ships.
+func (s *Server) play(sess *session) error {
+	s.startTurn(sess)
+	var choice string
+	for choice != "ai" && choice != "human" {
+		fmt.Fprintln(sess.conn, "Enter \"ai\" to play the AI, \"human\" to play someone else connected, or \"quit\"")
+		line, err := sess.input.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		choice = strings.ToLower(strings.TrimSpace(line))
+		if choice == "quit" {
+			return errQuit
+		}
+	}
+
+	sess.tui = terminal.NewTerminalUI(sess.input, sess.conn, s.Rules)
+	sess.tui.SetHintLimit(hintLimit)
+	s.startTurn(sess)
+	if err := sess.tui.SetUp(); err != nil {
+		return err
+	}
+	sess.conn.SetReadDeadline(time.Time{})
+
+	if choice == "ai" {
+		return s.playAI(sess)
+	}
+
+	s.mu.Lock()
+	opponent := s.waiting
+	if opponent == nil {
+		sess.matched, sess.stopped, sess.played = make(chan struct{}), make(chan struct{}), make(chan struct{})
+		s.waiting = sess
+	} else {
+		s.waiting = nil
+	}
+	s.mu.Unlock()
+
+	if opponent == nil {
+		if err := s.wait(sess); err != nil {
+			return err
+		}
+		// the opponent's goroutine plays the game.
+		<-sess.played
+		return nil
+	}
+
+	// stop the waiting client reading its input, so we can play the game with it.
+	close(opponent.matched)
+	opponent.conn.SetReadDeadline(time.Now())
+	<-opponent.stopped
+	s.playHumans(opponent, sess)
+	close(opponent.played)
+	return nil
+}
+
+// wait waits for another client to be matched with the waiting client sess,
+// returning an error if the client disconnects before then.
+func (s *Server) wait(sess *session) error {
+	fmt.Fprintln(sess.conn, "Waiting for an opponent to connect...")
+	defer close(sess.stopped)
+	defer sess.conn.SetReadDeadline(time.Time{})
+
+	for {
+		select {
+		case <-sess.matched:
+			return nil
+		default:
+		}
+
+		sess.conn.SetReadDeadline(time.Now().Add(waitPoll))
+		_, err := sess.input.ReadString('\n')
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			continue
+		}
+		if err != nil {
+			s.mu.Lock()
+			waiting := s.waiting == sess
+			if waiting {
+				s.waiting = nil
+			}
+			s.mu.Unlock()
+			if waiting {
+				return err
+			}
+			// an opponent turned up as we disconnected; the game ends as soon as it reads from us.
+			return nil
+		}
+		fmt.Fprintln(sess.conn, "Still waiting for an opponent...")
+	}
+}
+
+// turnTimeout returns how long clients have to take each turn.
+func (s *Server) turnTimeout() time.Duration {
+	if s.TurnTimeout <= 0 {
+		return DefaultTurnTimeout
+	}
+	return s.TurnTimeout
+}
+
+// startTurn gives the client until the turn timeout to take their turn, or finish what they're doing.
+func (s *Server) startTurn(sess *session) {
+	sess.conn.SetReadDeadline(time.Now().Add(s.turnTimeout()))
+}
+
+// playAI plays a game between the client and an AI.
+func (s *Server) playAI(sess *session) error {
+	opponent, err := s.NewAI(s.Rules)
+	if err != nil {
+		return err
+	}
+	if c, ok := opponent.(io.Closer); ok {
+		// bots need stopping, even if the client leaves mid game.
+		defer c.Close()
+	}
+	defer sess.conn.SetReadDeadline(time.Time{})
+
+	result, err := battleship.PlayGame(sess.tui, opponent, func(player int) {
+		if player == 1 {
+			s.startTurn(sess)
+		} else {
+			fmt.Fprintln(sess.conn, "The AI is taking its turn")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if result.Winner == 1 {
+		return battleship.GameOver(sess.tui, opponent)
+	}
+	return battleship.GameOver(opponent, sess.tui)
+}
+
+// playHumans plays a game between two clients, the first having waited for the second, and so going first.
+// If either disconnects the other is told, and carries on to a new game.
+func (s *Server) playHumans(first, second *session) {
+	s.logf("%v playing %v", first.conn.RemoteAddr(), second.conn.RemoteAddr())
+	sessions := [2]*session{first, second}
+	for i, sess := range sessions {
+		fmt.Fprintf(sess.conn, "Playing against %v\n", sessions[1-i].conn.RemoteAddr())
+		defer sess.conn.SetReadDeadline(time.Time{})
+	}
+
+	result, err := battleship.PlayGame(first.tui, second.tui, func(player int) {
+		s.startTurn(sessions[player-1])
+		fmt.Fprintln(sessions[player-1].conn, "Your turn")
+		fmt.Fprintln(sessions[2-player].conn, "Waiting for your opponent to take their turn...")
+	})
+	if err != nil {
+		for _, sess := range sessions {
+			fmt.Fprintln(sess.conn, "The game is over; a player left, or took too long to take their turn")
+		}
+		return
+	}
+
+	winner, loser := sessions[result.Winner-1], sessions[2-result.Winner]
+	if err := battleship.GameOver(winner.tui, loser.tui); err != nil {
+		s.logf("ending game: %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stewi1014/battleship"
+	"github.com/stewi1014/battleship/ai"
+)
+
+// client is a test client of the server, playing by typed lines as a netcat user would.
+type client struct {
+	t     *testing.T
+	conn  net.Conn
+	input *bufio.Reader
+}
+
+// newServer returns a server playing small fleets against the classic AI.
+func newServer() *Server {
+	return &Server{
+		Rules: battleship.Rules{Fleet: battleship.SmallFleet},
+		NewAI: func(rules battleship.Rules) (battleship.Player, error) {
+			return ai.NewAI(rules), nil
+		},
+	}
+}
+
+// serve starts s on a random port, returning its address, and a func to stop it.
+func serve(t *testing.T, s *Server) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	return l.Addr().String(), func() { l.Close() }
+}
+
+func dial(t *testing.T, addr string) *client {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(20 * time.Second))
+	return &client{t: t, conn: conn, input: bufio.NewReader(conn)}
+}
+
+// send types a line.
+func (c *client) send(line string) {
+	if _, err := fmt.Fprintln(c.conn, line); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// expect reads lines until one contains any of the given texts, returning the text found.
+func (c *client) expect(texts ...string) string {
+	for {
+		line, err := c.input.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("waiting for %q: %v", texts, err)
+		}
+		for _, text := range texts {
+			if strings.Contains(line, text) {
+				return text
+			}
+		}
+	}
+}
+
+// join chooses who to play, and places the destroyer at a1 to a3 and the patrol boat at c1 and d1.
+func (c *client) join(choice string) {
+	c.expect(`"quit"`)
+	c.send(choice)
+	c.expect("Enter Destroyer")
+	c.send("a1 right")
+	c.expect("Enter Patrol Boat")
+	c.send("c1 up")
+	c.expect("All ships placed")
+}
+
+// shoot takes a turn, shooting at position.
+func (c *client) shoot(position string) {
+	c.expect("Enter shot location")
+	c.send(position)
+	c.expect("Press enter")
+	c.send("")
+}
+
+func TestServerHumans(t *testing.T) {
+	addr, stop := serve(t, newServer())
+	defer stop()
+
+	first := dial(t, addr)
+	defer first.conn.Close()
+	first.join("human")
+	first.expect("Waiting for an opponent")
+
+	second := dial(t, addr)
+	defer second.conn.Close()
+	second.join("human")
+	first.expect("Playing against")
+	second.expect("Playing against")
+
+	// first sinks every ship, while second misses along row j.
+	hits := []string{"a1", "a2", "a3", "c1", "d1"}
+	for i, hit := range hits {
+		first.shoot(hit)
+		if i < len(hits)-1 {
+			second.shoot(fmt.Sprintf("j%v", i+1))
+		}
+	}
+	first.expect("You won!")
+	second.expect("You lost.")
+
+	// both go back to choosing who to play.
+	first.expect(`"quit"`)
+	second.expect(`"quit"`)
+}
+
+func TestServerWaitingDisconnect(t *testing.T) {
+	s := newServer()
+	addr, stop := serve(t, s)
+	defer stop()
+
+	gone := dial(t, addr)
+	gone.join("human")
+	gone.expect("Waiting for an opponent")
+	gone.conn.Close()
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		s.mu.Lock()
+		waiting := s.waiting
+		s.mu.Unlock()
+		if waiting == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("disconnected client is still waiting for an opponent")
+		}
+	}
+
+	// the next client waits, rather than playing the one that left.
+	next := dial(t, addr)
+	defer next.conn.Close()
+	next.join("human")
+	next.expect("Waiting for an opponent")
+	next.send("hello?")
+	next.expect("Still waiting")
+}
+
+func TestServerAI(t *testing.T) {
+	addr, stop := serve(t, newServer())
+	defer stop()
+
+	c := dial(t, addr)
+	defer c.conn.Close()
+	c.join("ai")
+
+	// shoot every position in turn until the game is over.
+	for i := 0; ; i++ {
+		if c.expect("Enter shot location", "You won!", "You lost.") != "Enter shot location" {
+			break
+		}
+		c.send(battleship.FormatPosition(i%battleship.BoardSize, i/battleship.BoardSize))
+		c.expect("Press enter")
+		c.send("")
+	}
+
+	c.expect(`"quit"`)
+	c.send("quit")
+	if _, err := c.input.ReadString('\n'); err == nil {
+		t.Error("connection still open after quitting")
+	}
+}
+
+func TestServerSmallBoard(t *testing.T) {
+	s := newServer()
+	s.Rules.Size = 5
+	addr, stop := serve(t, s)
+	defer stop()
+
+	c := dial(t, addr)
+	defer c.conn.Close()
+	c.expect(`"quit"`)
+	c.send("ai")
+
+	// ships must stay within the bottom left 5x5 positions, right up to the edge.
+	c.expect("Enter Destroyer")
+	c.send("a4 right")
+	c.expect("ship is off the board")
+	c.expect("Enter Destroyer")
+	c.send("a3 right")
+	c.expect("Enter Patrol Boat")
+	c.send("e5 down")
+	c.expect("All ships placed")
+
+	// as must shots.
+	c.expect("Enter shot location")
+	c.send("f1")
+	c.expect("f1 is off the board")
+	c.send("a6")
+	c.expect("a6 is off the board")
+	c.send("e5")
+	c.expect("Press enter")
+	c.send("")
+	c.expect("Enter shot location")
+}
+
+func TestServerTurnTimeout(t *testing.T) {
+	s := newServer()
+	s.TurnTimeout = 100 * time.Millisecond
+	addr, stop := serve(t, s)
+	defer stop()
+
+	first := dial(t, addr)
+	defer first.conn.Close()
+	first.join("human")
+	first.expect("Waiting for an opponent")
+
+	second := dial(t, addr)
+	defer second.conn.Close()
+	second.join("human")
+
+	// first never takes their turn, so second isn't kept waiting forever.
+	second.expect("took too long")
+	second.expect(`"quit"`)
+}
+
+func TestServerSetUpTimeout(t *testing.T) {
+	testCases := []struct {
+		desc string
+		// input is what the client types before going quiet.
+		input []string
+	}{
+		{
+			desc: "choosing who to play",
+		},
+		{
+			desc:  "placing ships",
+			input: []string{"ai", "a1 right"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := newServer()
+			s.TurnTimeout = 100 * time.Millisecond
+			addr, stop := serve(t, s)
+			defer stop()
+
+			c := dial(t, addr)
+			defer c.conn.Close()
+			for _, line := range tC.input {
+				c.send(line)
+			}
+			c.expect("took too long")
+			if _, err := io.Copy(ioutil.Discard, c.input); err != nil {
+				t.Errorf("connection not closed after taking too long; %v", err)
+			}
+		})
+	}
+}
+
+func TestServerHintLimit(t *testing.T) {
+	addr, stop := serve(t, newServer())
+	defer stop()
+
+	c := dial(t, addr)
+	defer c.conn.Close()
+	c.join("ai")
+	for i := 0; i < hintLimit; i++ {
+		c.expect("Enter shot location")
+		c.send("hint")
+		c.expect("Hint:")
+	}
+	c.expect("Enter shot location")
+	c.send("hint")
+	c.expect("No hints left")
+}
+
+// closingAI is an AI that records being closed, as bots must be.
+type closingAI struct {
+	*ai.AI
+	closed chan struct{}
+}
+
+func (c closingAI) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestServerAIClosed(t *testing.T) {
+	closed := make(chan struct{})
+	s := newServer()
+	s.NewAI = func(rules battleship.Rules) (battleship.Player, error) {
+		return closingAI{AI: ai.NewAI(rules), closed: closed}, nil
+	}
+	addr, stop := serve(t, s)
+	defer stop()
+
+	c := dial(t, addr)
+	c.join("ai")
+	c.expect("Enter shot location")
+	c.conn.Close()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("AI not closed after its opponent left")
+	}
+}
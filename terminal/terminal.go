@@ -0,0 +1,372 @@
+// Package terminal has a battleship player that plays by typing into the terminal.
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/stewi1014/battleship"
+	"github.com/stewi1014/battleship/ai"
+)
+
+// NewTerminalUI creates a new terminal game session for a human player, playing by the given rules.
+// The player types into input, and is shown the game on output.
+// If input is a bufio.Reader it is read from directly, so it can be shared with other readers of the same input.
+func NewTerminalUI(input io.Reader, output io.Writer, rules battleship.Rules) *TerminalUI {
+	return &TerminalUI{
+		board:   rules.NewBoard(),
+		input:   bufio.NewReader(input),
+		output:  output,
+		rules:   rules,
+		arsenal: rules.Arsenal,
+		sunk:    make(map[byte]battleship.Point),
+	}
+}
+
+// hintBudget is how long is spent working out a hint.
+const hintBudget = 500 * time.Millisecond
+
+// TerminalUI is a terminal session of battleship.
+type TerminalUI struct {
+	board   battleship.Board
+	score   int
+	rules   battleship.Rules
+	arsenal battleship.Arsenal
+	attacks int
+
+	// sunk holds where each of the opponent's sunk ships was sunk, for hints.
+	sunk   map[byte]battleship.Point
+	hinter *ai.Sampler
+	rng    *rand.Rand
+	// hints counts the hints given, up to hintLimit if it's set.
+	hints, hintLimit int
+
+	// incoming holds the opponent's shots since the player's last turn.
+	incoming []battleship.IncomingFire
+
+	// Reader for user input, and the Writer it's shown the game on.
+	input  *bufio.Reader
+	output io.Writer
+}
+
+// SetHintLimit limits how many hints the player can have in a game, as each keeps a CPU busy working it out.
+// 0, the default, gives as many as they ask for.
+func (g *TerminalUI) SetHintLimit(limit int) {
+	g.hintLimit = limit
+}
+
+// SetUp asks the user to place their ships on the board, writing them to it.
+func (g *TerminalUI) SetUp() error {
+	fleet := g.rules.GetFleet()
+	ships := fleet.Ships()
+
+	// repeatedly ask for location and direction of ship placement until a sucessful position is given.
+	for i := 0; i < len(ships); {
+		class, _ := fleet.Class(ships[i])
+		fmt.Fprint(g.output, g.board.Format(fleet))
+		if !class.Straight() {
+			// show the player what shape they're placing, and where it's placed from.
+			fmt.Fprint(g.output, class)
+		}
+		fmt.Fprintf(g.output, "Enter %v Location and direction. (h for help)\n", class.Name)
+		str, err := g.input.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		str = strings.ToLower(strings.TrimSpace(str))
+
+		if str == "h" {
+			fmt.Fprint(g.output, `Syntax: [location] [direction]
+Possible directions are "up", "down", "left", "right"
+location is a-j for vertical position, 1-10 for horizontal position.
+i.e h4 down
+`)
+			if g.rules.Diagonal {
+				fmt.Fprintln(g.output, `Straight ships can also be placed diagonally, with "upleft", "upright", "downleft" and "downright"`)
+			}
+			if !fleet.Straight() {
+				fmt.Fprint(g.output, `Odd shaped ships are drawn facing up, and are placed at the position marked @.
+Add "mirror" after the direction to flip the ship left to right before it is turned.
+i.e h4 left mirror
+`)
+			}
+			if g.rules.NoTouching {
+				fmt.Fprintln(g.output, "Ships may not touch eachother, even diagonally.")
+			}
+			continue
+		}
+
+		// get our arguments; position, direction and optionally mirror.
+		args := strings.Fields(str)
+		if len(args) != 2 && (len(args) != 3 || args[2] != "mirror") {
+			fmt.Fprintln(g.output, "wrong number of arguments; can only take 2, or 3 to mirror")
+			continue
+		}
+
+		x, y, err := battleship.ParsePosition(args[0])
+		if err != nil {
+			fmt.Fprintln(g.output, err)
+			continue
+		}
+
+		direction, ok := battleship.DirectionNames[args[1]]
+		if !ok {
+			fmt.Fprintf(g.output, "unknown direction %v\n", args[1])
+			continue
+		}
+		if len(args) == 3 {
+			direction |= battleship.Mirrored
+		}
+
+		err = g.rules.PlaceShip(&g.board, x, y, direction, ships[i])
+		if err != nil {
+			fmt.Fprintln(g.output, err)
+			continue
+		}
+
+		// sucess, move to the next ship
+		i++
+	}
+
+	fmt.Fprintln(g.output, g.board.Format(fleet))
+	fmt.Fprintln(g.output, "All ships placed")
+	return nil
+}
+
+// GetBoard implements Player.
+func (g *TerminalUI) GetBoard() *battleship.Board {
+	return &g.board
+}
+
+// IncomingFire implements Player.
+func (g *TerminalUI) IncomingFire(fire battleship.IncomingFire) {
+	g.incoming = append(g.incoming, fire)
+}
+
+// Turn implements Player.
+// it tells the player where their opponent shot since their last turn, asks them to take a turn and executes it.
+func (g *TerminalUI) Turn(remote battleship.Link) (won bool, err error) {
+	g.reportIncoming()
+	defer func() { g.incoming = nil }()
+
+	fmt.Fprintln(g.output, "Current score", g.score)
+	if g.rules.Arsenal != (battleship.Arsenal{}) {
+		fmt.Fprintln(g.output, "Special weapons left:", g.arsenal)
+	}
+
+	for streak := 0; ; streak++ {
+		if streak > 0 {
+			fmt.Fprintln(g.output, "Shoot again! Streak", streak)
+		}
+		// the opponent's latest shots are highlighted on the bottom board.
+		fmt.Fprint(g.output, g.board.FormatIncoming(g.rules.GetFleet(), g.incoming))
+
+		at, err := g.askAttack()
+		if err != nil {
+			return false, err
+		}
+		g.arsenal.Use(at.Weapon)
+		g.attacks++
+
+		shots, contact := at.Launch(&g.board, remote)
+		if at.Weapon == battleship.WeaponSonar {
+			if contact {
+				fmt.Fprintln(g.output, "Sonar contact!")
+			} else {
+				fmt.Fprintln(g.output, "Sonar found nothing.")
+			}
+		}
+		if at.Weapon == battleship.WeaponTorpedo && len(shots) == 0 {
+			fmt.Fprintln(g.output, "The torpedo didn't hit anything.")
+		}
+		for _, shot := range shots {
+			if shot.Hit {
+				fmt.Fprintf(g.output, "Hit at %v!\n", battleship.FormatPosition(shot.X, shot.Y))
+				if shot.Sunk != 0 {
+					fmt.Fprintf(g.output, "You sunk their %v!\n", g.rules.GetFleet().Name(shot.Sunk))
+					g.score++
+					g.sunk[shot.Sunk] = battleship.Point{X: shot.X, Y: shot.Y}
+				}
+			} else {
+				fmt.Fprintf(g.output, "Miss at %v!\n", battleship.FormatPosition(shot.X, shot.Y))
+			}
+		}
+
+		hit, sunk := battleship.Result(shots)
+		if g.rules.Won(g.score) || !g.rules.ShootAgain(hit, sunk) {
+			break
+		}
+	}
+
+	fmt.Fprintln(g.output, "Press enter to finish turn")
+	g.input.ReadString('\n')
+
+	return g.rules.Won(g.score), nil
+}
+
+// reportIncoming tells the player where their opponent shot since their last turn.
+func (g *TerminalUI) reportIncoming() {
+	if len(g.incoming) == 0 {
+		return
+	}
+	fmt.Fprintln(g.output, "Incoming fire:")
+	fleet := g.rules.GetFleet()
+	for _, fire := range g.incoming {
+		position := battleship.FormatPosition(fire.X, fire.Y)
+		switch {
+		case fire.Weapon == battleship.WeaponSonar && fire.Hit:
+			fmt.Fprintf(g.output, "Their sonar found a ship around %v!\n", position)
+		case fire.Weapon == battleship.WeaponSonar:
+			fmt.Fprintf(g.output, "Their sonar found nothing around %v.\n", position)
+		case fire.Sunk != 0:
+			fmt.Fprintf(g.output, "They sunk your %v at %v!\n", fleet.Name(fire.Ship), position)
+		case fire.Hit:
+			fmt.Fprintf(g.output, "They hit your %v at %v!\n", fleet.Name(fire.Ship), position)
+		default:
+			fmt.Fprintf(g.output, "They missed at %v.\n", position)
+		}
+	}
+}
+
+// askAttack asks the player for an attack to make, until they give a valid one.
+func (g *TerminalUI) askAttack() (battleship.Attack, error) {
+	for {
+		fmt.Fprintln(g.output, "Enter shot location (h for help)")
+		str, err := g.input.ReadString('\n')
+		if err != nil {
+			return battleship.Attack{}, err
+		}
+		str = strings.ToLower(strings.TrimSpace(str))
+
+		if str == "hint" || str == "hint map" {
+			if g.hintLimit > 0 && g.hints >= g.hintLimit {
+				fmt.Fprintf(g.output, "No hints left; you've had all %v for this game.\n", g.hintLimit)
+				continue
+			}
+			g.hint(str == "hint map")
+			continue
+		}
+
+		if str == "h" {
+			fmt.Fprintln(g.output, "Syntax: [location]")
+			fmt.Fprintln(g.output, "location is a-j for vertical position, 1-10 for horizontal position. \n i.e. g6")
+			fmt.Fprintln(g.output, `"hint" suggests where to shoot, and "hint map" also shows the chance of a ship being at each location.`)
+			if g.rules.Arsenal != (battleship.Arsenal{}) {
+				fmt.Fprintf(g.output, `Special weapons:
+sonar [location]            finds if any ship is in the 3x3 area around location.
+airstrike [location]        shoots the %v positions in the row around location.
+torpedo [row] [left|right]  fires a torpedo along a row from the left or right, hitting the first ship in its path.
+i.e. torpedo c right
+`, battleship.AirstrikeRadius*2+1)
+			}
+			continue
+		}
+
+		at := battleship.Attack{Weapon: battleship.WeaponShot}
+		args := strings.Fields(str)
+		if len(args) > 1 {
+			// a special weapon
+			var ok bool
+			for weapon, name := range battleship.WeaponNames {
+				if args[0] == name {
+					at.Weapon, ok = weapon, true
+				}
+			}
+			if !ok {
+				fmt.Fprintf(g.output, "unknown weapon %v\n", args[0])
+				continue
+			}
+			if !g.arsenal.Has(at.Weapon) {
+				fmt.Fprintf(g.output, "You don't have any %v left!\n", args[0])
+				continue
+			}
+			args = args[1:]
+		}
+
+		if at.Weapon == battleship.WeaponTorpedo {
+			at.Y = int(args[0][0] - 'a')
+			if len(args[0]) != 1 || !g.rules.InBounds(0, at.Y) {
+				fmt.Fprintf(g.output, "invalid row %v\n", args[0])
+				continue
+			}
+			if len(args) > 2 {
+				fmt.Fprintln(g.output, "wrong number of arguments")
+				continue
+			}
+			if len(args) > 1 {
+				if args[1] != "left" && args[1] != "right" {
+					fmt.Fprintf(g.output, "unknown direction %v; torpedoes fire from the left or right\n", args[1])
+					continue
+				}
+				at.FromRight = args[1] == "right"
+			}
+			return at, nil
+		}
+
+		if len(args) != 1 {
+			fmt.Fprintln(g.output, "wrong number of arguments")
+			continue
+		}
+
+		at.X, at.Y, err = battleship.ParsePosition(args[0])
+		if err != nil {
+			fmt.Fprintln(g.output, err)
+			continue
+		}
+		if !g.rules.InBounds(at.X, at.Y) {
+			fmt.Fprintf(g.output, "%v is off the board\n", args[0])
+			continue
+		}
+
+		if at.Weapon == battleship.WeaponShot && g.board.PlayerHasShot(at.X, at.Y) {
+			fmt.Fprintln(g.output, "You've already shot that location!")
+			continue
+		}
+
+		return at, nil
+	}
+}
+
+// hint suggests the shot a SamplerAI would take, given what the player knows, optionally showing the chance of a ship being at each position.
+func (g *TerminalUI) hint(showMap bool) {
+	if g.hinter == nil {
+		g.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+		g.hinter = ai.NewSampler(g.rules, g.rng)
+	}
+	g.hints++
+
+	heat, samples := g.hinter.Heatmap(&g.board, g.sunk, hintBudget)
+	if samples == 0 {
+		fmt.Fprintln(g.output, "No hint; no layout of ships fits what you've seen.")
+		return
+	}
+	if showMap {
+		fmt.Fprint(g.output, g.board.FormatHeatmap(&heat))
+	}
+	x, y := ai.HottestShot(&g.board, &heat, g.rng)
+	fmt.Fprintf(g.output, "Hint: shoot %v, there's a %.0f%% chance of a ship there.\n", battleship.FormatPosition(x, y), 100*heat[x][y])
+}
+
+// GameOver implements GameEnder, showing the player a summary of the game.
+func (g *TerminalUI) GameOver(won bool, opponent *battleship.Board) error {
+	if won {
+		fmt.Fprintln(g.output, "You won!")
+	} else {
+		fmt.Fprintln(g.output, "You lost.")
+	}
+
+	var hits int
+	for x := 0; x < battleship.BoardSize; x++ {
+		for y := 0; y < battleship.BoardSize; y++ {
+			if g.board.PlayerHasHit(x, y) {
+				hits++
+			}
+		}
+	}
+	fmt.Fprintf(g.output, "Attacks: %v, hits: %v, ships sunk: %v, hints used: %v\n", g.attacks, hits, g.score, g.hints)
+	return nil
+}
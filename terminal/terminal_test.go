@@ -0,0 +1,272 @@
+package terminal
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stewi1014/battleship"
+)
+
+func TestTerminalHint(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		input     string
+		hintLimit int
+		wantHints int
+	}{
+		{
+			desc:  "no hints",
+			input: "b2\n",
+		},
+		{
+			desc:      "hints",
+			input:     "hint\nhint map\nb2\n",
+			wantHints: 2,
+		},
+		{
+			desc:      "hint limit",
+			input:     "hint\nhint\nhint\nb2\n",
+			hintLimit: 2,
+			wantHints: 2,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var output bytes.Buffer
+			g := NewTerminalUI(strings.NewReader(tC.input), &output, battleship.Rules{})
+			g.SetHintLimit(tC.hintLimit)
+			at, err := g.askAttack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if at.X != 1 || at.Y != 1 {
+				t.Errorf("got attack at %v, want b2", battleship.FormatPosition(at.X, at.Y))
+			}
+			if g.hints != tC.wantHints {
+				t.Errorf("got %v hints, want %v", g.hints, tC.wantHints)
+			}
+			if limited := strings.Contains(output.String(), "No hints left"); limited != (tC.hintLimit > 0) {
+				t.Errorf("hints limited %v, with a limit of %v", limited, tC.hintLimit)
+			}
+		})
+	}
+}
+
+func TestTerminalAttack(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		rules battleship.Rules
+		input string
+		want  battleship.Attack
+		// wantOutput is shown before the attack is accepted.
+		wantOutput string
+	}{
+		{
+			desc:  "shot",
+			input: "b2\n",
+			want:  battleship.Attack{Weapon: battleship.WeaponShot, X: 1, Y: 1},
+		},
+		{
+			desc:       "off a small board",
+			rules:      battleship.Rules{Size: 5},
+			input:      "f1\na6\nb2\n",
+			want:       battleship.Attack{Weapon: battleship.WeaponShot, X: 1, Y: 1},
+			wantOutput: "a6 is off the board",
+		},
+		{
+			desc:  "far corner of a small board",
+			rules: battleship.Rules{Size: 5},
+			input: "e5\n",
+			want:  battleship.Attack{Weapon: battleship.WeaponShot, X: 4, Y: 4},
+		},
+		{
+			desc:       "just off the edge of a small board",
+			rules:      battleship.Rules{Size: 5},
+			input:      "e6\nf5\ne5\n",
+			want:       battleship.Attack{Weapon: battleship.WeaponShot, X: 4, Y: 4},
+			wantOutput: "f5 is off the board",
+		},
+		{
+			desc:       "airstrike off a small board",
+			rules:      battleship.Rules{Size: 5, Arsenal: battleship.AdvancedArsenal},
+			input:      "airstrike a6\nairstrike a5\n",
+			want:       battleship.Attack{Weapon: battleship.WeaponAirstrike, X: 4},
+			wantOutput: "a6 is off the board",
+		},
+		{
+			desc:       "torpedo off a small board",
+			rules:      battleship.Rules{Size: 5, Arsenal: battleship.AdvancedArsenal},
+			input:      "torpedo f right\ntorpedo e right\n",
+			want:       battleship.Attack{Weapon: battleship.WeaponTorpedo, Y: 4, FromRight: true},
+			wantOutput: "invalid row f",
+		},
+		{
+			desc:       "torpedo with a misspelt direction",
+			rules:      battleship.Rules{Arsenal: battleship.AdvancedArsenal},
+			input:      "torpedo c rihgt\ntorpedo c right\n",
+			want:       battleship.Attack{Weapon: battleship.WeaponTorpedo, Y: 2, FromRight: true},
+			wantOutput: "unknown direction rihgt",
+		},
+		{
+			desc:  "torpedo from the left",
+			rules: battleship.Rules{Arsenal: battleship.AdvancedArsenal},
+			input: "torpedo c left\n",
+			want:  battleship.Attack{Weapon: battleship.WeaponTorpedo, Y: 2},
+		},
+		{
+			desc:  "named shot",
+			input: "shot c4\n",
+			want:  battleship.Attack{Weapon: battleship.WeaponShot, X: 3, Y: 2},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var output bytes.Buffer
+			g := NewTerminalUI(strings.NewReader(tC.input), &output, tC.rules)
+			at, err := g.askAttack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if at != tC.want {
+				t.Errorf("got attack %+v, want %+v", at, tC.want)
+			}
+			if !strings.Contains(output.String(), tC.wantOutput) {
+				t.Errorf("got output %q, want it to include %q", output.String(), tC.wantOutput)
+			}
+		})
+	}
+}
+
+// update rewrites the golden transcripts with the output of the tests, i.e. go test ./terminal -update
+var update = flag.Bool("update", false, "update the golden transcripts in testdata")
+
+// fixedPlayer is an opponent with a fixed board, taking one scripted shot each turn.
+type fixedPlayer struct {
+	board battleship.Board
+	shots []string
+}
+
+func (p *fixedPlayer) GetBoard() *battleship.Board {
+	return &p.board
+}
+
+func (p *fixedPlayer) IncomingFire(battleship.IncomingFire) {}
+
+func (p *fixedPlayer) Turn(remote battleship.Link) (bool, error) {
+	if len(p.shots) == 0 {
+		return false, nil
+	}
+	x, y, err := battleship.ParsePosition(p.shots[0])
+	if err != nil {
+		return false, err
+	}
+	p.shots = p.shots[1:]
+	remote.TakeShot(x, y)
+	return false, nil
+}
+
+// TestTerminalTranscript plays scripted games against a fixed opponent, comparing everything the player is shown with testdata/[name].golden.
+func TestTerminalTranscript(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		name  string
+		rules battleship.Rules
+		input []string
+		// fire is where the opponent shoots, after each of the player's turns.
+		fire []string
+	}{
+		{
+			desc:  "placing and shooting",
+			name:  "classic",
+			rules: battleship.Rules{Fleet: battleship.SmallFleet},
+			input: []string{
+				// placing; help, then mistakes before each ship is placed.
+				"h", "a1", "z1 up", "a1 sideways", "a1 right", "a2 up", "c1 up",
+				// shooting; each turn ends with enter.
+				"h", "e5", "",
+				"e5", "e6", "",
+				"e7", "",
+				"a1", "",
+				"b9", "",
+				"c9", "",
+			},
+			fire: []string{"j10", "a1", "a2", "a3", "b5"},
+		},
+		{
+			desc:  "special weapons and bonus shots",
+			name:  "advanced",
+			rules: battleship.Rules{Fleet: battleship.SmallFleet, Arsenal: battleship.AdvancedArsenal, BonusShot: battleship.BonusOnHit},
+			input: []string{
+				"a1 right", "c1 up",
+				"h", "sonar e6", "",
+				"sonar a1", "",
+				"sonar a1", "laser a1", "airstrike e6", "torpedo b right", "c9", "",
+			},
+			fire: []string{"c1", "d1"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			// the opponent's destroyer is at e5 to e7, and patrol boat at b9 and c9.
+			opponent := &fixedPlayer{board: tC.rules.NewBoard(), shots: tC.fire}
+			ships := tC.rules.GetFleet().Ships()
+			if err := tC.rules.PlaceShip(&opponent.board, 4, 4, battleship.Right, ships[0]); err != nil {
+				t.Fatal(err)
+			}
+			if err := tC.rules.PlaceShip(&opponent.board, 8, 1, battleship.Up, ships[1]); err != nil {
+				t.Fatal(err)
+			}
+
+			var output bytes.Buffer
+			g := NewTerminalUI(strings.NewReader(strings.Join(tC.input, "\n")+"\n"), &output, tC.rules)
+			if err := g.SetUp(); err != nil {
+				t.Fatal(err)
+			}
+			// the input running out ends the game with an error, if the script doesn't win it.
+			for {
+				won, err := g.Turn(battleship.NewLocalLink(opponent))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if won {
+					break
+				}
+				if _, err := opponent.Turn(battleship.NewLocalLink(g)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := battleship.GameOver(g, opponent); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", tC.name+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, output.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotLines, wantLines := strings.Split(output.String(), "\n"), strings.Split(string(want), "\n")
+			for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+				var got, want string
+				if i < len(gotLines) {
+					got = gotLines[i]
+				}
+				if i < len(wantLines) {
+					want = wantLines[i]
+				}
+				if got != want {
+					t.Fatalf("transcript differs from %v at line %v; got %q, want %q", golden, i+1, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+// Package tournament plays players against each other, and rates them.
+package tournament
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stewi1014/battleship"
+	"github.com/stewi1014/battleship/ai"
+	"github.com/stewi1014/battleship/bot"
+)
+
+// Participant is a player in a tournament, created afresh for each game it plays.
+type Participant struct {
+	Name string
+	New  func(rules battleship.Rules) (battleship.Player, error)
+}
+
+// ParseParticipant parses a participant as given on the command line;
+// "ai" or "sampler" for the built in AIs, "nash:" followed by the path to a strategy file,
+// or "bot:" followed by the command to run an external bot.
+// AIs place their ships for the given difficulty, the classic AI plays with params and hunts with the opening shots,
+// and bots are given timeout to reply.
+func ParseParticipant(arg string, difficulty int, params ai.AIParams, opening []battleship.Point, budget, timeout time.Duration) (Participant, error) {
+	switch {
+	case arg == "ai":
+		return Participant{Name: arg, New: func(rules battleship.Rules) (battleship.Player, error) {
+			a := ai.NewAI(rules)
+			a.SetDifficulty(difficulty)
+			a.SetParams(params)
+			a.SetOpening(opening)
+			return a, nil
+		}}, nil
+	case arg == "sampler":
+		return Participant{Name: arg, New: func(rules battleship.Rules) (battleship.Player, error) {
+			a := ai.NewSamplerAI(rules, budget)
+			a.SetDifficulty(difficulty)
+			return a, nil
+		}}, nil
+	case strings.HasPrefix(arg, "nash:"):
+		strategy, err := ai.LoadStrategy(strings.TrimPrefix(arg, "nash:"))
+		if err != nil {
+			return Participant{}, err
+		}
+		return Participant{Name: arg, New: func(rules battleship.Rules) (battleship.Player, error) {
+			return ai.NewStrategyAI(rules, strategy)
+		}}, nil
+	case strings.HasPrefix(arg, "bot:"):
+		command, err := bot.ParseBotCommand(strings.TrimPrefix(arg, "bot:"))
+		if err != nil {
+			return Participant{}, err
+		}
+		if len(command) == 0 {
+			return Participant{}, fmt.Errorf("no command given for %v", arg)
+		}
+		return Participant{Name: arg, New: func(rules battleship.Rules) (battleship.Player, error) {
+			return bot.NewBotPlayer(command, rules, timeout)
+		}}, nil
+	default:
+		return Participant{}, fmt.Errorf("unknown participant %v; want \"ai\", \"sampler\", \"nash:[path]\" or \"bot:[command]\"", arg)
+	}
+}
+
+// Constants for the Elo ratings in a tournament ladder.
+const (
+	eloStart = 1500
+	eloK     = 16
+)
+
+// Tournament plays every pairing of its participants against each other.
+type Tournament struct {
+	Rules        battleship.Rules
+	Participants []Participant
+	// Games is how many games each pairing plays, with the first move alternating between them.
+	Games int
+}
+
+// TournamentResult is the outcome of a Tournament.
+type TournamentResult struct {
+	Participants []string `json:"participants"`
+	// Crosstable holds the number of games each participant won against each other participant, indexed [winner][loser].
+	Crosstable [][]int `json:"crosstable"`
+	// Ladder holds the standings of the participants, highest rated first.
+	Ladder []Standing `json:"ladder"`
+	// Forfeits holds why games were forfeited, i.e. a bot crashing.
+	Forfeits []string `json:"forfeits,omitempty"`
+	// GameOverErrors holds the errors from telling participants their game was over, i.e. a bot crashing at the end of a game.
+	GameOverErrors []string `json:"gameOverErrors,omitempty"`
+}
+
+// Standing is a participant's place in a tournament ladder.
+type Standing struct {
+	Name    string  `json:"name"`
+	Rating  float64 `json:"rating"`
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	WinRate float64 `json:"winRate"`
+	// ShotsToWin is the average number of shots landed in the games won, not counting forfeits, or 0 if none were.
+	// Each shot of an airstrike counts, and sonar pings don't, so it's comparable between rules with and without weapons or bonus shots.
+	ShotsToWin float64 `json:"shotsToWin"`
+}
+
+// Run plays the tournament.
+// progress, if not nil, is called after each game with the number of games played so far, and in total.
+func (t Tournament) Run(progress func(played, total int)) TournamentResult {
+	n := len(t.Participants)
+	r := TournamentResult{
+		Participants: make([]string, n),
+		Crosstable:   make([][]int, n),
+	}
+	ratings := make([]float64, n)
+	// shots and wins count only games played out, not won by forfeit.
+	shots := make([]int, n)
+	wins := make([]int, n)
+	for i, p := range t.Participants {
+		r.Participants[i] = p.Name
+		r.Crosstable[i] = make([]int, n)
+		ratings[i] = eloStart
+	}
+
+	total := n * (n - 1) / 2 * t.Games
+	played := 0
+	for a := 0; a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			for game := 0; game < t.Games; game++ {
+				first, second := a, b
+				if game%2 == 1 {
+					first, second = b, a
+				}
+
+				g := t.play(first, second)
+				winner, loser := g.winner, g.loser
+				if g.err != nil {
+					r.Forfeits = append(r.Forfeits, fmt.Sprintf("%v forfeited against %v: %v", t.Participants[loser].Name, t.Participants[winner].Name, g.err))
+				} else {
+					shots[winner] += g.winnerShots
+					wins[winner]++
+				}
+				if err := g.gameOverErrs[0]; err != nil {
+					r.GameOverErrors = append(r.GameOverErrors, fmt.Sprintf("%v failed at the end of its win against %v: %v", t.Participants[winner].Name, t.Participants[loser].Name, err))
+				}
+				if err := g.gameOverErrs[1]; err != nil {
+					r.GameOverErrors = append(r.GameOverErrors, fmt.Sprintf("%v failed at the end of its loss against %v: %v", t.Participants[loser].Name, t.Participants[winner].Name, err))
+				}
+
+				r.Crosstable[winner][loser]++
+				expected := 1 / (1 + math.Pow(10, (ratings[loser]-ratings[winner])/400))
+				ratings[winner] += eloK * (1 - expected)
+				ratings[loser] -= eloK * (1 - expected)
+
+				played++
+				if progress != nil {
+					progress(played, total)
+				}
+			}
+		}
+	}
+
+	for i, name := range r.Participants {
+		s := Standing{
+			Name:   name,
+			Rating: math.Round(ratings[i]),
+		}
+		for j := range r.Participants {
+			s.Wins += r.Crosstable[i][j]
+			s.Losses += r.Crosstable[j][i]
+		}
+		if s.Wins+s.Losses > 0 {
+			s.WinRate = float64(s.Wins) / float64(s.Wins+s.Losses)
+		}
+		if wins[i] > 0 {
+			s.ShotsToWin = float64(shots[i]) / float64(wins[i])
+		}
+		r.Ladder = append(r.Ladder, s)
+	}
+	sort.SliceStable(r.Ladder, func(i, j int) bool {
+		return r.Ladder[i].Rating > r.Ladder[j].Rating
+	})
+
+	return r
+}
+
+// game is the outcome of a single game in a tournament.
+type game struct {
+	winner, loser int
+	// winnerShots is the number of shots the winner landed.
+	winnerShots int
+	// err is why the loser forfeited, or nil if the game was played out.
+	err error
+	// gameOverErrs holds the errors from telling the winner, then the loser, that the game was over.
+	gameOverErrs [2]error
+}
+
+// play plays a single game between the participants numbered first and second, with first going first.
+// If a participant can't be created or returns an error, it forfeits and the error is returned.
+func (t Tournament) play(first, second int) game {
+	p1, err := t.Participants[first].New(t.Rules)
+	if err != nil {
+		return game{winner: second, loser: first, err: err}
+	}
+	p2, err := t.Participants[second].New(t.Rules)
+	if err != nil {
+		// p1 still needs stopping if it's a bot.
+		if c, ok := p1.(io.Closer); ok {
+			c.Close()
+		}
+		return game{winner: first, loser: second, err: err}
+	}
+
+	result, err := battleship.PlayGame(p1, p2, nil)
+	g := game{winner: first, loser: second, err: err}
+	winner, loser := p1, p2
+	if result.Winner != 1 {
+		g.winner, g.loser = second, first
+		winner, loser = p2, p1
+	}
+	for _, move := range result.Moves[result.Winner-1] {
+		g.winnerShots += len(move.Shots)
+	}
+
+	// each is told separately, rather than with battleship.GameOver,
+	// so the loser is still told, and stopped if it's a bot, when the winner fails.
+	if e, ok := winner.(battleship.GameEnder); ok {
+		g.gameOverErrs[0] = e.GameOver(true, loser.GetBoard())
+	}
+	if e, ok := loser.(battleship.GameEnder); ok {
+		g.gameOverErrs[1] = e.GameOver(false, winner.GetBoard())
+	}
+	return g
+}
+
+// String returns the tournament result as text tables; the crosstable, then the ladder.
+func (r TournamentResult) String() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(&sb, "Wins of each row against each column")
+	for i := range r.Participants {
+		fmt.Fprintf(w, "\t%v", i+1)
+	}
+	fmt.Fprintln(w)
+	for i, name := range r.Participants {
+		fmt.Fprintf(w, "%v %v", i+1, name)
+		for j := range r.Participants {
+			if i == j {
+				fmt.Fprint(w, "\t-")
+				continue
+			}
+			fmt.Fprintf(w, "\t%v", r.Crosstable[i][j])
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+
+	fmt.Fprintln(&sb)
+	fmt.Fprintln(w, "Rank\tName\tRating\tWins\tLosses\tWin %\tShots to win")
+	for i, s := range r.Ladder {
+		fmt.Fprintf(w, "%v\t%v\t%.0f\t%v\t%v\t%.1f\t%.1f\n", i+1, s.Name, s.Rating, s.Wins, s.Losses, 100*s.WinRate, s.ShotsToWin)
+	}
+	w.Flush()
+
+	for _, forfeit := range r.Forfeits {
+		fmt.Fprintln(&sb, forfeit)
+	}
+	for _, err := range r.GameOverErrors {
+		fmt.Fprintln(&sb, err)
+	}
+	return sb.String()
+}
@@ -0,0 +1,139 @@
+package tournament
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stewi1014/battleship"
+	"github.com/stewi1014/battleship/ai"
+)
+
+func TestTournament(t *testing.T) {
+	classic, err := ParseParticipant("ai", battleship.DifficultyEasy, ai.DefaultAIParams(), nil, 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := Participant{
+		Name: "broken",
+		New: func(rules battleship.Rules) (battleship.Player, error) {
+			return nil, errors.New("broken")
+		},
+	}
+
+	testCases := []struct {
+		desc         string
+		rules        battleship.Rules
+		participants []Participant
+		games        int
+		wantForfeits int
+	}{
+		{
+			desc:         "ai against itself",
+			participants: []Participant{classic, classic},
+			games:        10,
+		},
+		{
+			desc:         "three way",
+			participants: []Participant{classic, classic, classic},
+			games:        4,
+		},
+		{
+			desc:         "bonus shots",
+			rules:        battleship.Rules{BonusShot: battleship.BonusOnHit},
+			participants: []Participant{classic, classic},
+			games:        4,
+		},
+		{
+			desc:         "forfeits",
+			participants: []Participant{classic, broken},
+			games:        4,
+			wantForfeits: 4,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tournament := Tournament{
+				Rules:        tC.rules,
+				Participants: tC.participants,
+				Games:        tC.games,
+			}
+			r := tournament.Run(nil)
+
+			if len(r.Forfeits) != tC.wantForfeits {
+				t.Errorf("got forfeits %v, want %v", r.Forfeits, tC.wantForfeits)
+			}
+
+			n := len(tC.participants)
+			var ratings float64
+			for i := 0; i < n; i++ {
+				for j := i + 1; j < n; j++ {
+					if got := r.Crosstable[i][j] + r.Crosstable[j][i]; got != tC.games {
+						t.Errorf("participants %v and %v played %v games, want %v", i, j, got, tC.games)
+					}
+				}
+			}
+			for i, s := range r.Ladder {
+				ratings += s.Rating
+				if s.Wins+s.Losses != tC.games*(n-1) {
+					t.Errorf("%v played %v games, want %v", s.Name, s.Wins+s.Losses, tC.games*(n-1))
+				}
+				// the classic fleet takes 17 hits to sink, and there are only 100 positions to shoot.
+				if tC.wantForfeits == 0 && s.Wins > 0 && (s.ShotsToWin < 17 || s.ShotsToWin > 100) {
+					t.Errorf("%v took %v shots to win, want between 17 and 100", s.Name, s.ShotsToWin)
+				}
+				if i > 0 && s.Rating > r.Ladder[i-1].Rating {
+					t.Errorf("ladder not sorted by rating; %v", r.Ladder)
+				}
+			}
+			// rounding can lose a point or so.
+			if diff := ratings - float64(n*eloStart); diff < -float64(n) || diff > float64(n) {
+				t.Errorf("ratings total %v, want %v", ratings, n*eloStart)
+			}
+		})
+	}
+}
+
+// gameEnder is an AI that counts being told the game is over, and fails if err is set.
+type gameEnder struct {
+	*ai.AI
+	told *int
+	err  error
+}
+
+// GameOver implements battleship.GameEnder
+func (g gameEnder) GameOver(won bool, opponent *battleship.Board) error {
+	*g.told++
+	return g.err
+}
+
+func TestTournamentGameOver(t *testing.T) {
+	var failingTold, fineTold int
+	newParticipant := func(name string, told *int, err error) Participant {
+		return Participant{
+			Name: name,
+			New: func(rules battleship.Rules) (battleship.Player, error) {
+				return gameEnder{AI: ai.NewAI(rules), told: told, err: err}, nil
+			},
+		}
+	}
+	tournament := Tournament{
+		Participants: []Participant{
+			newParticipant("failing", &failingTold, errors.New("crashed")),
+			newParticipant("fine", &fineTold, nil),
+		},
+		Games: 4,
+	}
+	r := tournament.Run(nil)
+
+	if len(r.Forfeits) != 0 {
+		t.Errorf("got forfeits %v, want none", r.Forfeits)
+	}
+	if len(r.GameOverErrors) != 4 {
+		t.Errorf("got game over errors %v, want 4", r.GameOverErrors)
+	}
+	// fine must be told even when failing wins and fails first.
+	if failingTold != 4 || fineTold != 4 {
+		t.Errorf("failing told %v times and fine %v times, want 4 each", failingTold, fineTold)
+	}
+}
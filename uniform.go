@@ -0,0 +1,75 @@
+package battleship
+
+import (
+	"math/rand"
+)
+
+// NewUniformGenerator returns a UniformGenerator for games played by the given rules.
+func NewUniformGenerator(rules Rules) *UniformGenerator {
+	g := &UniformGenerator{
+		rules: rules,
+		ships: rules.GetFleet().Ships(),
+	}
+	for _, ship := range g.ships {
+		g.placements = append(g.placements, rules.Placements(ship))
+	}
+	return g
+}
+
+// UniformGenerator creates boards with fleet layouts picked uniformly at random from every legal layout.
+// RandomBoard places one ship at a time, retrying until each fits, which favours some layouts;
+// the first ships are placed anywhere, and later ships fill whatever gaps are left.
+// Instead, the generator picks a placement for every ship at once, and starts over if any don't fit together,
+// so every legal layout is equally likely.
+type UniformGenerator struct {
+	rules Rules
+	ships []byte
+
+	// placements holds every placement of each ship, indexed the same as ships.
+	placements [][][]Point
+}
+
+// uniformCheckTries is how many layouts are tried before checking the fleet can fit on the board at all.
+const uniformCheckTries = 100000
+
+// Board returns a board with a uniformly random fleet layout.
+// It panics if the rules can't be played by, as the fleet would never fit; see Rules.Check.
+func (g *UniformGenerator) Board(rng *rand.Rand) Board {
+	for {
+		if b, ok := g.Try(rng, uniformCheckTries); ok {
+			return b
+		}
+		mustCheck(g.rules)
+	}
+}
+
+// Try makes up to tries attempts at picking a layout, returning false if none were legal.
+func (g *UniformGenerator) Try(rng *rand.Rand, tries int) (Board, bool) {
+tryAgain:
+	for i := 0; i < tries; i++ {
+		b := g.rules.NewBoard()
+		for j, ship := range g.ships {
+			if len(g.placements[j]) == 0 {
+				return Board{}, false
+			}
+			cells := g.placements[j][rng.Intn(len(g.placements[j]))]
+
+			for _, cell := range cells {
+				if b[cell.X][cell.Y]&shipMask > 0 || g.rules.NoTouching && b.HasShipNear(cell.X, cell.Y) {
+					continue tryAgain
+				}
+			}
+			for _, cell := range cells {
+				b[cell.X][cell.Y] = ship
+			}
+		}
+		return b, true
+	}
+	return Board{}, false
+}
+
+// UniformBoard creates a board with a fleet layout picked uniformly at random from every layout allowed by the rules.
+// When making many boards, a UniformGenerator saves working out the placements each time.
+func UniformBoard(rng *rand.Rand, rules Rules) Board {
+	return NewUniformGenerator(rules).Board(rng)
+}
@@ -0,0 +1,123 @@
+package battleship
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// exactOccupancy enumerates every legal layout allowed by the rules,
+// returning the fraction of layouts with a ship at each position.
+func exactOccupancy(rules Rules) (occupancy [BoardSize][BoardSize]float64) {
+	ships := rules.GetFleet().Ships()
+	var placements [][][]Point
+	for _, ship := range ships {
+		placements = append(placements, rules.Placements(ship))
+	}
+
+	var layouts float64
+	var counts [BoardSize][BoardSize]float64
+	var b Board
+	var place func(i int)
+	place = func(i int) {
+		if i == len(ships) {
+			layouts++
+			for x := 0; x < BoardSize; x++ {
+				for y := 0; y < BoardSize; y++ {
+					if b[x][y]&shipMask > 0 {
+						counts[x][y]++
+					}
+				}
+			}
+			return
+		}
+
+	placements:
+		for _, cells := range placements[i] {
+			for _, cell := range cells {
+				if b[cell.X][cell.Y]&shipMask > 0 || rules.NoTouching && b.HasShipNear(cell.X, cell.Y) {
+					continue placements
+				}
+			}
+			for _, cell := range cells {
+				b[cell.X][cell.Y] = ships[i]
+			}
+			place(i + 1)
+			for _, cell := range cells {
+				b[cell.X][cell.Y] = 0
+			}
+		}
+	}
+	place(0)
+
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
+			occupancy[x][y] = counts[x][y] / layouts
+		}
+	}
+	return
+}
+
+// The generator is checked against the exact distribution of small games, where every layout can be counted.
+// Each position's occupancy over many samples should be within a few standard deviations of the exact occupancy.
+func TestUniformGenerator(t *testing.T) {
+	smallFleet := Fleet{ClassicFleet[2], ClassicFleet[4], {Name: "Dinghy", Symbol: 'G', Cells: Line(2)}}
+	testCases := []struct {
+		desc  string
+		rules Rules
+	}{
+		{
+			desc:  "small fleet",
+			rules: Rules{Size: 5, Fleet: smallFleet},
+		},
+		{
+			desc:  "small fleet no touching",
+			rules: Rules{Size: 5, Fleet: smallFleet, NoTouching: true},
+		},
+		{
+			desc:  "small fleet diagonal",
+			rules: Rules{Size: 5, Fleet: smallFleet, Diagonal: true},
+		},
+		{
+			desc:  "shapes",
+			rules: Rules{Size: 5, Fleet: Fleet{ShapesFleet[0], ShapesFleet[2]}},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			const samples = 100000
+			exact := exactOccupancy(tC.rules)
+
+			rng := rand.New(rand.NewSource(1))
+			generator := NewUniformGenerator(tC.rules)
+			var counts [BoardSize][BoardSize]float64
+			for i := 0; i < samples; i++ {
+				b := generator.Board(rng)
+				for x := 0; x < BoardSize; x++ {
+					for y := 0; y < BoardSize; y++ {
+						if b[x][y]&shipMask > 0 {
+							counts[x][y]++
+						}
+					}
+				}
+			}
+
+			for x := 0; x < BoardSize; x++ {
+				for y := 0; y < BoardSize; y++ {
+					p := exact[x][y]
+					if p == 0 || p == 1 {
+						if counts[x][y] != p*samples {
+							t.Fatalf("%v: wanted occupancy %v, got %v", FormatPosition(x, y), p, counts[x][y]/samples)
+						}
+						continue
+					}
+
+					z := (counts[x][y] - p*samples) / math.Sqrt(samples*p*(1-p))
+					if math.Abs(z) > 5 {
+						t.Fatalf("%v: wanted occupancy %v, got %v (%.1f standard deviations out)", FormatPosition(x, y), p, counts[x][y]/samples, z)
+					}
+				}
+			}
+		})
+	}
+}
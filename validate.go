@@ -0,0 +1,128 @@
+package battleship
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BoardProblem is something wrong with a board, at the given positions.
+type BoardProblem struct {
+	Positions []Point
+	Problem   string
+}
+
+// String returns the problem, prefixed by its positions.
+func (p BoardProblem) String() string {
+	if len(p.Positions) == 0 {
+		return p.Problem
+	}
+	return FormatPoints(p.Positions) + ": " + p.Problem
+}
+
+// BoardError is the error returned by Validate, listing everything wrong with a board.
+type BoardError []BoardProblem
+
+// Error implements error.
+func (e BoardError) Error() string {
+	problems := make([]string, len(e))
+	for i, p := range e {
+		problems[i] = p.String()
+	}
+	return "invalid board; " + strings.Join(problems, "; ")
+}
+
+// Validate checks a board could have come from a game played by the rules, returning a BoardError if not.
+// Every ship of the fleet must be on the board once, in a shape and place it can be placed in, and not touching another if the rules forbid it.
+// There must be no other ships, nothing off the part of the board being played on, and no impossible combination of flags.
+func (r Rules) Validate(b *Board) error {
+	var problems BoardError
+	problem := func(positions []Point, format string, a ...interface{}) {
+		problems = append(problems, BoardProblem{Positions: positions, Problem: fmt.Sprintf(format, a...)})
+	}
+	fleet := r.GetFleet()
+
+	// positions of each ship type, and flags that can't go together or off the board.
+	ships := make(map[byte][]Point)
+	var strays, offBoard, hitUnshot, hitWater []Point
+	for x := 0; x < BoardSize; x++ {
+		for y := 0; y < BoardSize; y++ {
+			p := Point{x, y}
+			if ship := b[x][y] & shipMask; ship > 0 {
+				if _, ok := fleet.Class(ship); ok {
+					ships[ship] = append(ships[ship], p)
+				} else {
+					strays = append(strays, p)
+				}
+			}
+			if !r.InBounds(x, y) && b[x][y]&(shipMask|playerShot|playerHit|opponentHit) > 0 {
+				offBoard = append(offBoard, p)
+			}
+			if b[x][y]&playerHit > 0 && b[x][y]&playerShot == 0 {
+				hitUnshot = append(hitUnshot, p)
+			}
+			if b[x][y]&playerHit > 0 && b[x][y]&playerWater > 0 {
+				hitWater = append(hitWater, p)
+			}
+		}
+	}
+	if len(strays) > 0 {
+		problem(strays, "ship not in the fleet of %v ships", len(fleet))
+	}
+	if len(offBoard) > 0 {
+		problem(offBoard, "ship or shot off the %vx%v board", r.GetSize(), r.GetSize())
+	}
+	if len(hitUnshot) > 0 {
+		problem(hitUnshot, "hit without being shot")
+	}
+	if len(hitWater) > 0 {
+		problem(hitWater, "hit where there is known to be water")
+	}
+
+	// occupied holds the positions of each ship checked so far, by index in the fleet.
+	occupied := make(map[int]Bitboard)
+	for i, ship := range fleet.Ships() {
+		class, _ := fleet.Class(ship)
+		cells := ships[ship]
+		switch {
+		case len(cells) == 0:
+			problem(nil, "%v is missing", class.Name)
+			continue
+		case len(cells) != len(class.Cells):
+			problem(cells, "%v covers %v positions, want %v", class.Name, len(cells), len(class.Cells))
+			continue
+		}
+
+		mask := BitboardOf(cells)
+		var placed bool
+		for _, placement := range r.Placements(ship) {
+			if BitboardOf(placement) == mask {
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			if class.Straight() {
+				problem(cells, "%v isn't in a straight, unbroken line the rules allow", class.Name)
+			} else {
+				problem(cells, "%v isn't in its shape, or is somewhere the rules don't allow", class.Name)
+			}
+			continue
+		}
+
+		if r.NoTouching {
+			for j := 0; j < i; j++ {
+				if other, ok := occupied[j]; ok && !mask.Halo().And(other).Empty() {
+					// each problem gets its own positions; appending to cells could overwrite those of the last.
+					touching := append(append([]Point(nil), cells...), other.Points()...)
+					problem(touching, "%v touches %v", class.Name, fleet[j].Name)
+				}
+			}
+		}
+		occupied[i] = mask
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
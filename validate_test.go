@@ -0,0 +1,170 @@
+package battleship
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	// every classic ship standing up from row a, in every other column from 1, so they don't touch.
+	var valid Board
+	for i, ship := range ClassicFleet.Ships() {
+		if err := valid.PlaceShip(2*i, 0, Up, ship); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		desc  string
+		rules Rules
+		edit  func(b *Board)
+		// want holds text each problem must have, in order.
+		want []string
+	}{
+		{
+			desc:  "valid",
+			rules: Rules{NoTouching: true},
+			edit:  func(b *Board) {},
+		},
+		{
+			desc: "missing ship",
+			edit: func(b *Board) {
+				for y := 0; y < 2; y++ {
+					b[8][y] = 0
+				}
+			},
+			want: []string{"Patrol Boat is missing"},
+		},
+		{
+			desc: "ship too long",
+			edit: func(b *Board) {
+				b[2][4] = ShipBattleship
+			},
+			want: []string{"a3 b3 c3 d3 e3: Battleship covers 5 positions, want 4"},
+		},
+		{
+			desc: "bent ship",
+			edit: func(b *Board) {
+				b[4][2] = 0
+				b[5][1] = ShipDestroyer
+			},
+			want: []string{"a5 b5 b6: Destroyer isn't in a straight, unbroken line"},
+		},
+		{
+			desc: "broken ship",
+			edit: func(b *Board) {
+				b[4][2] = 0
+				b[4][3] = ShipDestroyer
+			},
+			want: []string{"a5 b5 d5: Destroyer isn't in a straight, unbroken line"},
+		},
+		{
+			desc: "stray ship",
+			edit: func(b *Board) {
+				b[9][9] = 6 << 5
+			},
+			want: []string{"j10: ship not in the fleet of 5 ships"},
+		},
+		{
+			desc: "flags",
+			edit: func(b *Board) {
+				b[9][0] = playerHit
+				b[9][1] = playerShot | playerHit | playerWater
+			},
+			want: []string{"a10: hit without being shot", "b10: hit where there is known to be water"},
+		},
+		{
+			desc:  "touching",
+			rules: Rules{NoTouching: true},
+			edit: func(b *Board) {
+				for y := 0; y < 3; y++ {
+					b[4][y] = 0
+					b[3][y+2] = ShipDestroyer
+				}
+			},
+			want: []string{"Destroyer touches Battleship"},
+		},
+		{
+			desc:  "off the board",
+			rules: Rules{Size: 8},
+			edit: func(b *Board) {
+				b[9][9] = playerShot
+			},
+			want: []string{"a9 b9 j10: ship or shot off the 8x8 board", "a9 b9: Patrol Boat isn't in a straight, unbroken line"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			b := valid
+			tC.edit(&b)
+			err := tC.rules.Validate(&b)
+			if len(tC.want) == 0 {
+				if err != nil {
+					t.Fatalf("got error %v", err)
+				}
+				return
+			}
+
+			problems, ok := err.(BoardError)
+			if !ok {
+				t.Fatalf("got error %v, want a BoardError", err)
+			}
+			if len(problems) != len(tC.want) {
+				t.Fatalf("got %v, want %v problems", err, len(tC.want))
+			}
+			for i, want := range tC.want {
+				if !strings.Contains(problems[i].String(), want) {
+					t.Errorf("got problem %q, want %q", problems[i], want)
+				}
+			}
+		})
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for _, rules := range []Rules{{}, {NoTouching: true}, {Diagonal: true}, {Fleet: ShapesFleet, NoTouching: true}, {Size: 6, Fleet: SmallFleet}} {
+		for i := 0; i < 100; i++ {
+			b := RandomBoard(rng, rules)
+			if err := rules.Validate(&b); err != nil {
+				t.Fatalf("random board invalid; %v\n%v", err, b)
+			}
+		}
+	}
+}
+
+func TestValidateTouchingTwice(t *testing.T) {
+	// a carrier along row c, touching a patrol boat along row b, and another along row d.
+	patrolBoat := ShipClass{Name: "Patrol Boat", Symbol: 'P', Cells: Line(2)}
+	fleet := Fleet{patrolBoat, patrolBoat, ClassicFleet[0]}
+	ships := fleet.Ships()
+	rules := Rules{Fleet: fleet}
+	var b Board
+	for _, place := range []struct {
+		x, y int
+		ship byte
+	}{
+		{0, 1, ships[0]},
+		{0, 3, ships[1]},
+		{0, 2, ships[2]},
+	} {
+		if err := rules.PlaceShip(&b, place.x, place.y, Right, place.ship); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules.NoTouching = true
+	err := rules.Validate(&b)
+	problems, ok := err.(BoardError)
+	if !ok {
+		t.Fatalf("got error %v, want a BoardError", err)
+	}
+	want := []string{"c1 c2 c3 c4 c5 b1 b2: Carrier touches Patrol Boat", "c1 c2 c3 c4 c5 d1 d2: Carrier touches Patrol Boat"}
+	if len(problems) != len(want) {
+		t.Fatalf("got %v, want %v problems", err, len(want))
+	}
+	for i := range want {
+		if problems[i].String() != want[i] {
+			t.Errorf("got problem %q, want %q", problems[i], want[i])
+		}
+	}
+}
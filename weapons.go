@@ -0,0 +1,158 @@
+package battleship
+
+import (
+	"fmt"
+)
+
+// Constants for the kinds of attack a player can make on their turn.
+// Anything but a plain shot is a special weapon, with a limited number of uses set by the rules.
+const (
+	WeaponShot = iota
+	WeaponSonar
+	WeaponAirstrike
+	WeaponTorpedo
+)
+
+// WeaponNames maps the weapons to their names, as they are typed.
+var WeaponNames = map[int]string{
+	WeaponShot:      "shot",
+	WeaponSonar:     "sonar",
+	WeaponAirstrike: "airstrike",
+	WeaponTorpedo:   "torpedo",
+}
+
+// Sizes of the areas special weapons cover.
+const (
+	SonarRadius     = 1 // a 3x3 area
+	AirstrikeRadius = 2 // a 5 position row segment
+)
+
+// Arsenal counts the special weapons a player has.
+type Arsenal struct {
+	Sonars     int
+	Airstrikes int
+	Torpedoes  int
+}
+
+// AdvancedArsenal is the arsenal each player starts with in advanced mode.
+var AdvancedArsenal = Arsenal{
+	Sonars:     2,
+	Airstrikes: 1,
+	Torpedoes:  1,
+}
+
+// Has returns true if there is at least one of the given weapon left.
+// Plain shots are never used up.
+func (a *Arsenal) Has(weapon int) bool {
+	switch weapon {
+	case WeaponSonar:
+		return a.Sonars > 0
+	case WeaponAirstrike:
+		return a.Airstrikes > 0
+	case WeaponTorpedo:
+		return a.Torpedoes > 0
+	default:
+		return true
+	}
+}
+
+// Use takes one of the given weapon from the arsenal, returning false if there were none left.
+func (a *Arsenal) Use(weapon int) bool {
+	if !a.Has(weapon) {
+		return false
+	}
+	switch weapon {
+	case WeaponSonar:
+		a.Sonars--
+	case WeaponAirstrike:
+		a.Airstrikes--
+	case WeaponTorpedo:
+		a.Torpedoes--
+	}
+	return true
+}
+
+// String formats the arsenal as a string.
+func (a Arsenal) String() string {
+	return fmt.Sprintf("sonar: %v, airstrike: %v, torpedo: %v", a.Sonars, a.Airstrikes, a.Torpedoes)
+}
+
+// Attack is a single attack made by a player on their turn.
+type Attack struct {
+	Weapon int
+	// X is ignored for torpedoes, which travel along the whole of row Y.
+	X, Y int
+	// FromRight launches a torpedo from the right edge of the board instead of the left.
+	FromRight bool
+}
+
+// String returns the attack as it would be typed in the terminal, i.e. "airstrike b4".
+// Plain shots are given as just their position.
+func (at Attack) String() string {
+	switch at.Weapon {
+	case WeaponShot:
+		return FormatPosition(at.X, at.Y)
+	case WeaponTorpedo:
+		side := "left"
+		if at.FromRight {
+			side = "right"
+		}
+		return fmt.Sprintf("torpedo %c %v", 'a'+at.Y, side)
+	default:
+		return fmt.Sprintf("%v %v", WeaponNames[at.Weapon], FormatPosition(at.X, at.Y))
+	}
+}
+
+// Launch carries out the attack against remote, recording what was learnt on b.
+// It returns the shots that landed, and for a sonar ping, if it found a ship.
+// The attack should be checked for validity beforehand.
+func (at Attack) Launch(b *Board, remote Link) (shots []Shot, contact bool) {
+	switch at.Weapon {
+	case WeaponSonar:
+		contact = remote.Sonar(at.X, at.Y)
+		b.PlayerSonar(at.X, at.Y, contact)
+
+	case WeaponAirstrike:
+		shots = remote.Airstrike(at.X, at.Y)
+
+	case WeaponTorpedo:
+		shot, ok := remote.Torpedo(at.Y, at.FromRight)
+		// everything the torpedo passed through, that isn't an old hit, must be water.
+		x, mx, end := 0, 1, BoardSize
+		if at.FromRight {
+			x, mx, end = BoardSize-1, -1, -1
+		}
+		if ok {
+			end = shot.X
+		}
+		for ; x != end; x += mx {
+			if !b.PlayerHasHit(x, at.Y) {
+				b.PlayerMarkWater(x, at.Y)
+			}
+		}
+		if ok {
+			shots = []Shot{shot}
+		}
+
+	default:
+		hit, sunk := remote.TakeShot(at.X, at.Y)
+		shots = []Shot{{X: at.X, Y: at.Y, Hit: hit, Sunk: sunk}}
+	}
+
+	for _, shot := range shots {
+		b.PlayerShot(shot.X, shot.Y, shot.Hit)
+	}
+	return shots, contact
+}
+
+// Result summarises the shots from an attack,
+// returning true if any were hits, and the last ship sunk.
+func Result(shots []Shot) (hit bool, sunk byte) {
+	for _, shot := range shots {
+		hit = hit || shot.Hit
+		if shot.Sunk != 0 {
+			sunk = shot.Sunk
+		}
+	}
+	return
+}